@@ -7,13 +7,20 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jbwfu/syntex/cmd/syntex/options"
 	"github.com/jbwfu/syntex/internal/clipboard"
 	"github.com/jbwfu/syntex/internal/filter"
 	"github.com/jbwfu/syntex/internal/language"
 	"github.com/jbwfu/syntex/internal/packer"
+	"github.com/jbwfu/syntex/internal/project"
+	"github.com/jbwfu/syntex/internal/source"
 	"github.com/spf13/pflag"
 )
 
@@ -35,11 +42,19 @@ func main() {
 
 // run executes the main logic of the syntex command-line tool.
 func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) > 0 && args[0] == "unpack" {
+		return runUnpack(args[1:], os.Stdin, stdout, stderr)
+	}
+
 	opts, err := options.ParseFlags(args, stderr)
 	if err != nil {
 		return err
 	}
 
+	if opts.PrintConfig {
+		return opts.WriteSettings(stdout)
+	}
+
 	if opts.ShowVersion {
 		fmt.Fprintf(stdout, "syntex version: %s\n", version)
 		fmt.Fprintf(stdout, "git commit: %s\n", commit)
@@ -49,8 +64,11 @@ func run(args []string, stdout, stderr io.Writer) error {
 
 	var outputWriters []io.Writer
 
+	// Split output creates its part files only once the number of parts is known.
+	isSplit := opts.SplitBytes > 0 || opts.SplitTokens > 0
+
 	outputFile := opts.OutputFile
-	if outputFile != "" {
+	if outputFile != "" && !isSplit {
 		f, err := os.Create(outputFile)
 		if err != nil {
 			return fmt.Errorf("failed to create output file %q: %w", outputFile, err)
@@ -59,14 +77,21 @@ func run(args []string, stdout, stderr io.Writer) error {
 		outputWriters = append(outputWriters, f)
 	}
 
+	var clipboardOutput *detachableWriter
 	if opts.ToClipboard {
-		cw := clipboard.NewWriter()
+		cw := clipboard.NewLimitedWriter(opts.ClipboardMax)
 		defer func() {
 			if err := cw.Close(); err != nil {
 				fmt.Fprintf(stderr, "warning: failed to write to clipboard: %v\n", err)
 			}
 		}()
-		outputWriters = append(outputWriters, cw)
+		if len(outputWriters) == 0 {
+			// As the only output, the clipboard stops packing as soon as it fails.
+			outputWriters = append(outputWriters, cw)
+		} else {
+			clipboardOutput = &detachableWriter{w: cw}
+			outputWriters = append(outputWriters, clipboardOutput)
+		}
 	}
 
 	if len(outputWriters) == 0 {
@@ -75,11 +100,25 @@ func run(args []string, stdout, stderr io.Writer) error {
 
 	finalOutputWriter := io.MultiWriter(outputWriters...)
 
+	var src source.Source = source.NewOS()
+	var revision *source.Git
+	if opts.Rev != "" {
+		if revision, err = openRevision(opts.Rev); err != nil {
+			return err
+		}
+		src = revision
+	}
+
 	filterOpts := filter.Options{
 		DisableGitignore: opts.NoIgnore,
 		ExcludePatterns:  opts.ExcludePatterns,
 		IncludePatterns:  opts.IncludePatterns,
 		AllowDotfiles:    opts.Hidden,
+		IgnoreFiles:      opts.IgnoreFiles,
+	}
+	if revision != nil {
+		// Every file in a git tree is tracked, so ignore rules on disk do not apply.
+		filterOpts.DisableGitignore = true
 	}
 	filterManager, err := filter.NewManager(filterOpts)
 	if err != nil {
@@ -91,7 +130,7 @@ func run(args []string, stdout, stderr io.Writer) error {
 		return err
 	}
 
-	languageDetector := language.NewDetector()
+	languageDetector := language.NewSourceDetector(src)
 
 	var allTargets []string
 	allTargets = append(allTargets, opts.Targets...)
@@ -106,24 +145,210 @@ func run(args []string, stdout, stderr io.Writer) error {
 		}
 	}
 
-	p := packer.NewPacker(formatter, finalOutputWriter, filterManager, languageDetector)
+	packerOpts := packer.Options{
+		Tree:          opts.Tree,
+		LineNumbers:   opts.LineNumbers,
+		MaxTokens:     opts.MaxTokens,
+		PriorityGlobs: opts.PriorityGlobs,
+		SmallestFirst: opts.SmallestFirst,
+		Truncate:      opts.Truncate,
+		Source:        src,
+		Jobs:          opts.Jobs,
+	}
+	if opts.ChangedSince != "" || opts.Staged || opts.Unstaged || opts.DiffAgainst != "" {
+		if err := loadChanges(opts, &packerOpts); err != nil {
+			return err
+		}
+	}
+	if !opts.NoRedact {
+		packerOpts.Transforms = append(packerOpts.Transforms, packer.NewRedactSecretsTransform(stderr))
+	}
+	if opts.Outline {
+		packerOpts.Transforms = append(packerOpts.Transforms, packer.NewOutlineTransform())
+	}
+	if opts.StripComments {
+		packerOpts.Transforms = append(packerOpts.Transforms, packer.NewStripCommentsTransform())
+	}
+	if opts.Header {
+		header, err := buildHeader()
+		if err != nil {
+			return err
+		}
+		if revision != nil {
+			header.Commit = revision.Commit()
+		}
+		packerOpts.Header = header
+	}
+
+	p := packer.NewPacker(formatter, finalOutputWriter, filterManager, languageDetector, packerOpts)
 
 	plan, err := p.Plan(allTargets)
 	if err != nil {
 		return fmt.Errorf("planning phase failed: %w", err)
 	}
 
+	if opts.FailOnSecrets {
+		if found := p.FindSecrets(plan); len(found) > 0 {
+			for _, secret := range found {
+				fmt.Fprintf(stderr, "secret: %s in %s:%d\n", secret.Kind, secret.Path, secret.Line)
+			}
+			return fmt.Errorf("found %d possible secrets, aborting because of --fail-on-secrets", len(found))
+		}
+	}
+
+	// Plan has already counted tokens when enforcing a budget.
+	if (opts.CountTokens || opts.DryRun) && opts.MaxTokens == 0 {
+		p.CountTokens(plan)
+	}
+
+	if opts.CountTokens {
+		return printTokenReport(stdout, plan)
+	}
+
 	if opts.DryRun {
 		return printDryRun(stdout, plan, opts.OutputFormat)
 	}
 
+	if isSplit {
+		limit := packer.SplitLimit{Bytes: opts.SplitBytes, Tokens: opts.SplitTokens}
+		parts, err := p.ExecuteSplit(plan, limit, func(part, parts int) (io.WriteCloser, error) {
+			name := splitPartName(outputFile, part, parts)
+			f, err := os.Create(name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create output file %q: %w", name, err)
+			}
+			return f, nil
+		})
+		if err != nil {
+			return fmt.Errorf("execution phase failed: %w", err)
+		}
+		if parts > 1 {
+			fmt.Fprintf(stderr, "Output split into %d parts.\n", parts)
+		}
+		return nil
+	}
+
 	if err := p.Execute(plan); err != nil {
 		return fmt.Errorf("execution phase failed: %w", err)
 	}
+	if clipboardOutput != nil && clipboardOutput.err != nil {
+		return fmt.Errorf("output was not copied to the clipboard: %w", clipboardOutput.err)
+	}
 
 	return nil
 }
 
+// detachableWriter passes writes through to w until one fails, and then
+// discards the rest, so that a failing destination of an io.MultiWriter
+// does not stop the others.
+type detachableWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (d *detachableWriter) Write(p []byte) (int, error) {
+	if d.err == nil {
+		if _, err := d.w.Write(p); err != nil {
+			d.err = err
+		}
+	}
+	return len(p), nil
+}
+
+// splitPartName returns the file name of one part of a split output by
+// inserting a zero-padded part number before the extension, e.g.
+// "context.md" becomes "context.001.md". A single part keeps the original name.
+func splitPartName(path string, part, parts int) string {
+	if parts <= 1 {
+		return path
+	}
+	width := max(3, len(strconv.Itoa(parts)))
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%0*d%s", strings.TrimSuffix(path, ext), width, part, ext)
+}
+
+// loadChanges restricts packerOpts to the git changes selected by the
+// --changed-since, --staged, --unstaged and --diff-against flags for the
+// repository containing the current working directory. Staged and unstaged
+// changes together compare HEAD with the working tree.
+func loadChanges(opts *options.Options, packerOpts *packer.Options) error {
+	root, isRepo, err := project.FindRoot(".")
+	if err != nil {
+		return fmt.Errorf("failed to determine project root: %w", err)
+	}
+	if !isRepo {
+		return fmt.Errorf("--changed-since, --staged, --unstaged and --diff-against require a git repository")
+	}
+
+	var changes []project.Change
+	switch {
+	case opts.DiffAgainst != "":
+		changes, err = project.ChangesSince(root, opts.DiffAgainst)
+		if err == nil {
+			packerOpts.Diff, err = project.NewDiffer(root, opts.DiffAgainst)
+			packerOpts.DiffWithContent = opts.WithContent
+		}
+	case opts.ChangedSince != "":
+		changes, err = project.ChangesSince(root, opts.ChangedSince)
+	case opts.Staged && opts.Unstaged:
+		changes, err = project.ChangesSince(root, "HEAD")
+	case opts.Staged:
+		changes, err = project.StagedChanges(root)
+	default:
+		changes, err = project.UnstagedChanges(root)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compute git changes: %w", err)
+	}
+
+	// An empty, non-nil slice still restricts the plan.
+	if changes == nil {
+		changes = []project.Change{}
+	}
+	packerOpts.Changes = changes
+	return nil
+}
+
+// openRevision opens the tree of a git revision of the repository containing
+// the current working directory as the source of packed files.
+func openRevision(rev string) (*source.Git, error) {
+	root, isRepo, err := project.FindRoot(".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine project root: %w", err)
+	}
+	if !isRepo {
+		return nil, fmt.Errorf("--rev requires a git repository")
+	}
+	revision, err := source.NewGit(root, rev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revision: %w", err)
+	}
+	return revision, nil
+}
+
+// buildHeader collects the document metadata for the project containing the
+// current working directory.
+func buildHeader() (*packer.Header, error) {
+	root, isRepo, err := project.FindRoot(".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine project root: %w", err)
+	}
+
+	header := &packer.Header{
+		Title:       filepath.Base(root),
+		GeneratedAt: time.Now(),
+	}
+
+	if isRepo {
+		commit, err := project.HeadCommit(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not determine git commit: %v\n", err)
+		}
+		header.Commit = commit
+	}
+	return header, nil
+}
+
 // readNULSeparatedPathsFromStdin reads NUL-separated file paths from os.Stdin
 // and appends them to the provided targetList.
 func readNULSeparatedPathsFromStdin(targetList *[]string) error {
@@ -176,17 +401,72 @@ func printDryRun(w io.Writer, plan []packer.PlannedFile, format string) error {
 
 	fmt.Fprintf(w, "[Dry Run] Planning to process files using the '%s' format:\n", format)
 
-	maxLangLen := 0
+	maxLangLen, maxTokensLen, totalTokens, omitted, deleted := 0, 0, 0, 0, 0
 	for _, file := range plan {
-		if len(file.Language) > maxLangLen {
-			maxLangLen = len(file.Language)
+		maxLangLen = max(maxLangLen, len(file.Language))
+		maxTokensLen = max(maxTokensLen, len(strconv.Itoa(file.Tokens)))
+		switch {
+		case file.Omitted:
+			omitted++
+		case file.Deleted:
+			// A deleted file has tokens only if its removal diff is packed.
+			deleted++
+			totalTokens += file.Tokens
+		default:
+			totalTokens += file.Tokens
 		}
 	}
 
 	for _, file := range plan {
-		fmt.Fprintf(w, "%-*s  %s\n", maxLangLen, file.Language, file.Path)
+		var note string
+		switch {
+		case file.Deleted:
+			note = " (deleted)"
+		case file.Omitted:
+			note = " (omitted)"
+		case file.Truncated:
+			note = " (truncated)"
+		case file.RenamedFrom != "":
+			note = " (renamed from " + file.RenamedFrom + ")"
+		}
+		fmt.Fprintf(w, "%-*s  %*d  %s%s\n", maxLangLen, file.Language, maxTokensLen, file.Tokens, file.DisplayName(), note)
 	}
 
-	fmt.Fprintf(w, "\n[Dry Run] Total: %d\n", len(plan))
+	fmt.Fprintf(w, "\n[Dry Run] Total: %d files, ~%d tokens\n", len(plan)-omitted-deleted, totalTokens)
+	if omitted > 0 {
+		fmt.Fprintf(w, "[Dry Run] Omitted to fit the token budget: %d files\n", omitted)
+	}
+	if deleted > 0 {
+		fmt.Fprintf(w, "[Dry Run] Deleted: %d files\n", deleted)
+	}
+	return nil
+}
+
+// printTokenReport displays a table of estimated token counts per file,
+// sorted from the largest file to the smallest, followed by the total.
+func printTokenReport(w io.Writer, plan []packer.PlannedFile) error {
+	sorted := slices.DeleteFunc(slices.Clone(plan), func(file packer.PlannedFile) bool {
+		return file.Deleted && file.Tokens == 0
+	})
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Tokens > sorted[j].Tokens
+	})
+
+	totalTokens := 0
+	for _, file := range sorted {
+		totalTokens += file.Tokens
+	}
+
+	tokensWidth := max(len("TOKENS"), len(strconv.Itoa(totalTokens)))
+	langWidth := len("LANGUAGE")
+	for _, file := range sorted {
+		langWidth = max(langWidth, len(file.Language))
+	}
+
+	fmt.Fprintf(w, "%*s  %-*s  %s\n", tokensWidth, "TOKENS", langWidth, "LANGUAGE", "PATH")
+	for _, file := range sorted {
+		fmt.Fprintf(w, "%*d  %-*s  %s\n", tokensWidth, file.Tokens, langWidth, file.Language, file.Path)
+	}
+	fmt.Fprintf(w, "%*d  %-*s  Total (%d files)\n", tokensWidth, totalTokens, langWidth, "", len(sorted))
 	return nil
 }
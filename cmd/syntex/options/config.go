@@ -0,0 +1,326 @@
+package options
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jbwfu/syntex/internal/project"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// projectConfigName is the name of the configuration file looked up at the project root.
+	projectConfigName = ".syntex.yaml"
+	// targetsKey is the configuration key holding default target paths or globs.
+	targetsKey = "targets"
+	// profilesKey is the configuration key holding the named profiles.
+	profilesKey = "profiles"
+)
+
+// pathKeys are the configuration keys whose values are paths, resolved
+// against the directory of the configuration file.
+var pathKeys = []string{targetsKey, "ignore-file"}
+
+// untrustedKeys are the options a project configuration may not set, as it
+// comes with the project rather than from the user: writing the output to
+// a file, disabling secret redaction and copying to the clipboard.
+var untrustedKeys = []string{"output", "no-redact", "clipboard"}
+
+// config holds the settings of a configuration file. Settings are keyed by
+// long flag name, or targetsKey for the positional arguments.
+type config struct {
+	path     string
+	settings map[string][]string
+	profiles map[string]map[string][]string
+}
+
+// setting is a value of a flag or of the targets, with a description of
+// where it came from.
+type setting struct {
+	values []string
+	origin string
+}
+
+// loadConfigs reads the user configuration file and the project
+// configuration file, in ascending order of priority. Missing files are
+// skipped. Keys must name flags of fs, and the project configuration must
+// not set any of untrustedKeys.
+func loadConfigs(fs *pflag.FlagSet) ([]*config, error) {
+	var paths []string
+	if path := userConfigPath(); path != "" {
+		paths = append(paths, path)
+	}
+	root, _, err := project.FindRoot(".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find project root: %w", err)
+	}
+	projectPath := filepath.Join(root, projectConfigName)
+	paths = append(paths, projectPath)
+
+	var configs []*config
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := parseConfig(path, data, fs)
+		if err != nil {
+			return nil, err
+		}
+		if path == projectPath {
+			if err := checkProjectConfig(cfg); err != nil {
+				return nil, err
+			}
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// userConfigPath returns the path of the user configuration file,
+// $XDG_CONFIG_HOME/syntex/config.yaml, or "" if it cannot be determined.
+func userConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "syntex", "config.yaml")
+}
+
+// checkProjectConfig fails if a project configuration sets any of
+// untrustedKeys, by default or in a profile.
+func checkProjectConfig(cfg *config) error {
+	names := make([]string, 0, len(cfg.profiles))
+	for name := range cfg.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, key := range untrustedKeys {
+		if _, ok := cfg.settings[key]; ok {
+			return fmt.Errorf("%s: option %q cannot be set in a project configuration", cfg.path, key)
+		}
+		for _, name := range names {
+			if _, ok := cfg.profiles[name][key]; ok {
+				return fmt.Errorf("%s: profile %q: option %q cannot be set in a project configuration", cfg.path, name, key)
+			}
+		}
+	}
+	return nil
+}
+
+// parseConfig parses a YAML configuration file. Its top-level keys are
+// default settings, except profiles, which maps profile names to settings.
+// Relative paths are resolved against the directory of the file.
+func parseConfig(path string, data []byte, fs *pflag.FlagSet) (*config, error) {
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	cfg := &config{path: path, profiles: make(map[string]map[string][]string)}
+	if profiles, ok := raw[profilesKey]; ok {
+		delete(raw, profilesKey)
+		named, ok := profiles.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s must map profile names to settings", path, profilesKey)
+		}
+		for name, settings := range named {
+			fields, ok := settings.(map[string]any)
+			if !ok && settings != nil {
+				return nil, fmt.Errorf("%s: profile %q must map option names to values", path, name)
+			}
+			parsed, err := parseSettings(fields, fs)
+			if err != nil {
+				return nil, fmt.Errorf("%s: profile %q: %w", path, name, err)
+			}
+			resolvePaths(parsed, filepath.Dir(path))
+			cfg.profiles[name] = parsed
+		}
+	}
+
+	settings, err := parseSettings(raw, fs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	resolvePaths(settings, filepath.Dir(path))
+	cfg.settings = settings
+	return cfg, nil
+}
+
+// resolvePaths rewrites the relative values of pathKeys in settings, which
+// are relative to dir, to be relative to the working directory.
+func resolvePaths(settings map[string][]string, dir string) {
+	for _, key := range pathKeys {
+		for i, value := range settings[key] {
+			settings[key][i] = resolvePath(dir, value)
+		}
+	}
+}
+
+// resolvePath resolves a path or glob relative to dir. The result is
+// relative to the working directory where possible, and keeps a trailing
+// separator, which marks a directory target.
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "~") {
+		return path
+	}
+
+	resolved := filepath.Join(dir, path)
+	if filepath.IsAbs(resolved) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, resolved); err == nil {
+				resolved = rel
+			}
+		}
+	}
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		resolved += string(filepath.Separator)
+	}
+	return resolved
+}
+
+// parseSettings converts YAML values to flag values. Scalars become a single
+// value and lists one value per element, as if the flag were repeated.
+func parseSettings(fields map[string]any, fs *pflag.FlagSet) (map[string][]string, error) {
+	settings := make(map[string][]string, len(fields))
+	for key, value := range fields {
+		if key != targetsKey && !configurable(fs, key) {
+			return nil, fmt.Errorf("unknown option %q", key)
+		}
+
+		var values []string
+		switch v := value.(type) {
+		case []any:
+			for _, elem := range v {
+				s, err := scalarString(elem)
+				if err != nil {
+					return nil, fmt.Errorf("option %q: %w", key, err)
+				}
+				values = append(values, s)
+			}
+		default:
+			s, err := scalarString(v)
+			if err != nil {
+				return nil, fmt.Errorf("option %q: %w", key, err)
+			}
+			values = []string{s}
+		}
+		settings[key] = values
+	}
+	return settings, nil
+}
+
+// configurable reports whether a configuration file may set the flag named name.
+func configurable(fs *pflag.FlagSet, name string) bool {
+	switch name {
+	case "profile", "version", "print-config":
+		return false
+	}
+	return fs.Lookup(name) != nil
+}
+
+// scalarString formats a YAML scalar as a flag value.
+func scalarString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool, int, float64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
+
+// configSettings merges the default settings of configs with the settings
+// of the named profile, if any, later ones overriding earlier ones. A
+// profile defined in several configs is merged in the same order. It fails
+// if the profile is not defined in any config.
+func configSettings(configs []*config, profile string) (map[string]setting, error) {
+	merged := make(map[string]setting)
+	for _, cfg := range configs {
+		for key, values := range cfg.settings {
+			merged[key] = setting{values: values, origin: cfg.path}
+		}
+	}
+	if profile == "" {
+		return merged, nil
+	}
+
+	found := false
+	for _, cfg := range configs {
+		settings, ok := cfg.profiles[profile]
+		if !ok {
+			continue
+		}
+		found = true
+		for key, values := range settings {
+			merged[key] = setting{values: values, origin: fmt.Sprintf("%s (profile %s)", cfg.path, profile)}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("profile %q is not defined in any configuration file", profile)
+	}
+	return merged, nil
+}
+
+// applySettings sets every flag of fs that was not given on the command
+// line to its value in settings.
+func applySettings(fs *pflag.FlagSet, settings map[string]setting) error {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		if name != targetsKey {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if fs.Changed(name) {
+			continue
+		}
+		s := settings[name]
+		for _, value := range s.values {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("%s: %w", s.origin, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WriteSettings writes the value of every flag, and the targets, to w with
+// where each value came from: the command line, the environment, a
+// configuration file, or the default.
+func (o *Options) WriteSettings(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	origin := func(name string) string {
+		if origin, ok := o.origins[name]; ok {
+			return origin
+		}
+		return "default"
+	}
+
+	o.flags.VisitAll(func(f *pflag.Flag) {
+		if f.Name == "print-config" || f.Name == "version" {
+			return
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Name, f.Value.String(), origin(f.Name))
+	})
+	fmt.Fprintf(tw, "%s\t%s\t%s\n", targetsKey, "["+strings.Join(o.Targets, ",")+"]", origin(targetsKey))
+	return tw.Flush()
+}
@@ -0,0 +1,33 @@
+package options
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// envPrefix starts the names of the environment variables holding flag defaults.
+const envPrefix = "SYNTEX_"
+
+// envName returns the environment variable holding the default of a flag,
+// such as SYNTEX_FROM_STDIN_0 for --from-stdin-0.
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// envSettings returns the flag values set in the environment, including the
+// profile. Empty variables are ignored.
+func envSettings(fs *pflag.FlagSet) map[string]setting {
+	settings := make(map[string]setting)
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Name != "profile" && !configurable(fs, f.Name) {
+			return
+		}
+		name := envName(f.Name)
+		if value := os.Getenv(name); value != "" {
+			settings[f.Name] = setting{values: []string{value}, origin: "environment (" + name + ")"}
+		}
+	})
+	return settings
+}
@@ -10,6 +10,9 @@ import (
 	"github.com/spf13/pflag"
 )
 
+// defaultClipboardMax is the default size limit of clipboard output, in bytes.
+const defaultClipboardMax = 10_000_000
+
 // Options holds all parsed command-line flags for the syntex tool.
 type Options struct {
 	// Filtering options
@@ -18,20 +21,56 @@ type Options struct {
 	Unrestricted    bool
 	ExcludePatterns []string
 	IncludePatterns []string
+	IgnoreFiles     []string
+
+	// Git change options
+	ChangedSince string
+	Staged       bool
+	Unstaged     bool
+	DiffAgainst  string
+	WithContent  bool
+	Rev          string
 
 	// Input/Output options
 	OutputFormat  string
 	OutputFile    string
 	ToClipboard   bool
+	ClipboardMax  int
 	FromStdin0    bool
 	FromStdinLine bool
+	Header        bool
+	Tree          bool
+	LineNumbers   bool
+	Outline       bool
+	StripComments bool
+	SplitBytes    int
+	SplitTokens   int
+
+	// Token budget options
+	MaxTokens     int
+	PriorityGlobs []string
+	SmallestFirst bool
+	Truncate      bool
+
+	// Secret options
+	NoRedact      bool
+	FailOnSecrets bool
 
 	// Behavior options
 	DryRun      bool
+	CountTokens bool
+	Jobs        int
+	Profile     string
+	PrintConfig bool
 	ShowVersion bool
 
 	// Positional arguments
 	Targets []string
+
+	// flags is the parsed flag set, and origins describes where the value of
+	// each flag, and of the targets, came from.
+	flags   *pflag.FlagSet
+	origins map[string]string
 }
 
 // ParseFlags parses the command-line arguments and populates the Options struct.
@@ -43,20 +82,52 @@ func ParseFlags(args []string, stderr io.Writer) (*Options, error) {
 
 	// Filtering Flags
 	fs.BoolVarP(&opts.Hidden, "hidden", "H", false, "Include hidden files and directories.")
-	fs.BoolVarP(&opts.NoIgnore, "no-ignore", "I", false, "Do not respect .gitignore files.")
+	fs.BoolVarP(&opts.NoIgnore, "no-ignore", "I", false, "Do not respect .gitignore and .syntexignore files.")
 	fs.BoolVarP(&opts.Unrestricted, "unrestricted", "u", false, "Perform an unrestricted search, alias for --hidden --no-ignore.")
 	fs.StringSliceVarP(&opts.ExcludePatterns, "exclude", "E", nil, "Exclude files/directories matching the given glob pattern.")
 	fs.StringSliceVar(&opts.IncludePatterns, "include", nil, "Force-include files matching the given glob, bypassing ignore rules.")
+	fs.StringArrayVar(&opts.IgnoreFiles, "ignore-file", nil, "Read extra ignore rules in gitignore syntax from this file, relative to the working directory, or to the configuration file setting it. Repeatable.")
+
+	// Git Change Flags
+	fs.StringVar(&opts.ChangedSince, "changed-since", "", "Only pack files changed between this git revision and the working tree.")
+	fs.BoolVar(&opts.Staged, "staged", false, "Only pack files with staged changes.")
+	fs.BoolVar(&opts.Unstaged, "unstaged", false, "Only pack files with unstaged changes, including untracked files.")
+	fs.StringVar(&opts.DiffAgainst, "diff-against", "", "Pack the unified diff of every file changed since this git revision instead of its content.")
+	fs.BoolVar(&opts.WithContent, "with-content", false, "With --diff-against, pack the full content of every file before its diff.")
+	fs.StringVar(&opts.Rev, "rev", "", "Read files from this git commit, tag or branch instead of the working tree.")
 
 	// Input/Output Flags
-	fs.StringVarP(&opts.OutputFormat, "format", "f", "markdown", "Output format (markdown, md, org).")
+	fs.StringVarP(&opts.OutputFormat, "format", "f", "markdown", "Output format (markdown, md, org, xml, json, jsonl).")
 	fs.StringVarP(&opts.OutputFile, "output", "o", "", "Write output to a file instead of stdout.")
 	fs.BoolVarP(&opts.ToClipboard, "clipboard", "c", false, "Copy output to the system clipboard.")
+	opts.ClipboardMax = defaultClipboardMax
+	fs.Var(newSizeValue(&opts.ClipboardMax), "clipboard-max", "Abort --clipboard output once it exceeds this many bytes (e.g., 20m). 0 means no limit.")
 	fs.BoolVarP(&opts.FromStdin0, "from-stdin-0", "0", false, "Read NUL-separated paths from stdin (e.g., 'find . -print0').")
 	fs.BoolVarP(&opts.FromStdinLine, "from-stdin-line", "l", false, "Read newline-separated paths from stdin (e.g., 'ls -1').")
+	fs.Var(newSizeValue(&opts.SplitBytes), "split", "Split the output into numbered files of at most this many bytes (e.g., 200k). Requires --output.")
+	fs.Var(newSizeValue(&opts.SplitTokens), "split-tokens", "Split the output into numbered files of at most this many estimated tokens. Requires --output.")
+	fs.BoolVar(&opts.Tree, "tree", false, "Print a directory tree of all packed files before their contents (markdown, org).")
+	fs.BoolVarP(&opts.LineNumbers, "line-numbers", "n", false, "Prefix every line of every packed file with its line number.")
+	fs.BoolVar(&opts.Outline, "outline", false, "Pack Go files as declarations and signatures, leaving out function bodies.")
+	fs.BoolVar(&opts.StripComments, "strip-comments", false, "Remove comments and collapse blank lines, keeping string literals intact.")
+	fs.BoolVar(&opts.Header, "header", false, "Add a preamble with title, git commit and table of contents, and a closing summary.")
+
+	// Token Budget Flags
+	fs.IntVar(&opts.MaxTokens, "max-tokens", 0, "Fit the pack into an estimated token budget, omitting lower-priority files.")
+	fs.StringSliceVar(&opts.PriorityGlobs, "priority-glob", nil, "Under --max-tokens, keep files matching these globs first, in the given order.")
+	fs.BoolVar(&opts.SmallestFirst, "smallest-first", false, "Under --max-tokens, prefer smaller files instead of path order.")
+	fs.BoolVar(&opts.Truncate, "truncate", false, "Under --max-tokens, truncate the first file that does not fit instead of omitting it.")
+
+	// Secret Flags
+	fs.BoolVar(&opts.NoRedact, "no-redact", false, "Do not replace detected secrets such as API keys and passwords with [REDACTED:kind].")
+	fs.BoolVar(&opts.FailOnSecrets, "fail-on-secrets", false, "Abort with an error instead of packing if any file contains a detected secret.")
 
 	// Behavior Flags
 	fs.BoolVar(&opts.DryRun, "dry-run", false, "Print the list of files to be processed without generating output.")
+	fs.BoolVar(&opts.CountTokens, "count-tokens", false, "Print estimated token counts per file, largest first, without generating output.")
+	fs.IntVarP(&opts.Jobs, "jobs", "j", 0, "Number of files to analyze in parallel while planning. 0 means one per CPU.")
+	fs.StringVarP(&opts.Profile, "profile", "p", "", "Apply the settings of this profile from the configuration files.")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "Print the effective settings and where each came from, then exit.")
 	fs.BoolVarP(&opts.ShowVersion, "version", "V", false, "Print version information and exit.")
 
 	// Custom usage template
@@ -66,10 +137,21 @@ func ParseFlags(args []string, stderr io.Writer) (*Options, error) {
 		var b strings.Builder
 
 		fmt.Fprintf(&b, "A tool to pack multiple source files into a single context file.\n\n")
-		fmt.Fprintf(&b, "Usage:\n  %s [OPTIONS] [path_or_glob...]\n\n", progName)
+		fmt.Fprintf(&b, "Usage:\n  %s [OPTIONS] [path_or_glob...]\n", progName)
+		fmt.Fprintf(&b, "  %s unpack [OPTIONS] [pack]\n\n", progName)
 		fmt.Fprintf(&b, "Arguments:\n")
 		fmt.Fprintf(&b, "  [path_or_glob...]   Paths or glob patterns to search for files (optional).\n")
-		fmt.Fprintf(&b, "                        If omitted, input must be provided via stdin flags.\n\n")
+		fmt.Fprintf(&b, "                        If omitted, input must be provided via stdin flags,\n")
+		fmt.Fprintf(&b, "                        or all changed files are packed with --changed-since,\n")
+		fmt.Fprintf(&b, "                        --staged, --unstaged or --diff-against.\n")
+		fmt.Fprintf(&b, "                        Append :START-END (e.g., main.go:40-120) or #Symbol\n")
+		fmt.Fprintf(&b, "                        (e.g., main.go#run, Go only) to pack part of a file.\n\n")
+		fmt.Fprintf(&b, "Configuration:\n")
+		fmt.Fprintf(&b, "  Defaults for any option are read from ~/.config/syntex/config.yaml and from\n")
+		fmt.Fprintf(&b, "  .syntex.yaml at the project root, keyed by long option name, with \"targets\"\n")
+		fmt.Fprintf(&b, "  for the paths. Settings under \"profiles\" apply with -p/--profile.\n")
+		fmt.Fprintf(&b, "  Environment variables such as SYNTEX_FORMAT or SYNTEX_NO_IGNORE override the\n")
+		fmt.Fprintf(&b, "  configuration files, and options on the command line override both.\n\n")
 		fmt.Fprintf(&b, "Options:\n")
 
 		fmt.Fprint(output, b.String())
@@ -80,19 +162,102 @@ func ParseFlags(args []string, stderr io.Writer) (*Options, error) {
 		return nil, err
 	}
 
+	// Flags not given on the command line take their value from the
+	// environment, or else from the configuration files.
+	opts.flags = fs
+	opts.origins = make(map[string]string)
+	fs.Visit(func(f *pflag.Flag) { opts.origins[f.Name] = "command line" })
+	var settings map[string]setting
+	if !opts.ShowVersion {
+		env := envSettings(fs)
+		if s, ok := env["profile"]; ok && !fs.Changed("profile") {
+			opts.Profile = s.values[0]
+		}
+		configs, err := loadConfigs(fs)
+		if err != nil {
+			return nil, err
+		}
+		if settings, err = configSettings(configs, opts.Profile); err != nil {
+			return nil, err
+		}
+		for name, s := range env {
+			settings[name] = s
+		}
+		if err := applySettings(fs, settings); err != nil {
+			return nil, err
+		}
+		for name, s := range settings {
+			if _, ok := opts.origins[name]; !ok && name != targetsKey {
+				opts.origins[name] = s.origin
+			}
+		}
+	}
+
 	// Post-processing for combined flags
 	if opts.Unrestricted {
 		opts.Hidden = true
 		opts.NoIgnore = true
 	}
 
+	if opts.LineNumbers && (opts.Outline || opts.StripComments) {
+		return nil, fmt.Errorf("cannot use -n/--line-numbers with --outline or --strip-comments")
+	}
+
+	if opts.Jobs < 0 {
+		return nil, fmt.Errorf("-j/--jobs must not be negative")
+	}
+
+	if opts.MaxTokens < 0 {
+		return nil, fmt.Errorf("--max-tokens must not be negative")
+	}
+
+	if opts.SplitBytes > 0 || opts.SplitTokens > 0 {
+		if opts.OutputFile == "" {
+			return nil, fmt.Errorf("--split and --split-tokens require -o/--output")
+		}
+		if opts.ToClipboard {
+			return nil, fmt.Errorf("cannot use --split or --split-tokens with -c/--clipboard")
+		}
+	}
+
+	if opts.ChangedSince != "" && (opts.Staged || opts.Unstaged) {
+		return nil, fmt.Errorf("cannot use --changed-since with --staged or --unstaged")
+	}
+
+	if opts.DiffAgainst != "" {
+		if opts.ChangedSince != "" || opts.Staged || opts.Unstaged {
+			return nil, fmt.Errorf("cannot use --diff-against with --changed-since, --staged or --unstaged")
+		}
+		if opts.SplitBytes > 0 || opts.SplitTokens > 0 {
+			return nil, fmt.Errorf("cannot use --diff-against with --split or --split-tokens")
+		}
+	} else if opts.WithContent {
+		return nil, fmt.Errorf("--with-content requires --diff-against")
+	}
+
+	if opts.Rev != "" && (opts.ChangedSince != "" || opts.Staged || opts.Unstaged || opts.DiffAgainst != "") {
+		return nil, fmt.Errorf("cannot use --rev with --changed-since, --staged, --unstaged or --diff-against")
+	}
+
 	if opts.FromStdin0 && opts.FromStdinLine {
 		return nil, fmt.Errorf("cannot use both -0/--from-stdin-0 and -l/--from-stdin-line flags simultaneously")
 	}
 
 	if !opts.ShowVersion {
+		// Configured targets only stand in for paths when nothing else
+		// selects the input.
 		opts.Targets = fs.Args()
-		if len(opts.Targets) == 0 && len(opts.IncludePatterns) == 0 && !opts.FromStdin0 && !opts.FromStdinLine {
+		if len(opts.Targets) > 0 {
+			opts.origins[targetsKey] = "command line"
+		} else if s, ok := settings[targetsKey]; ok && len(opts.IncludePatterns) == 0 && !opts.FromStdin0 && !opts.FromStdinLine {
+			opts.Targets = s.values
+			opts.origins[targetsKey] = s.origin
+		}
+		if opts.PrintConfig {
+			return opts, nil
+		}
+		hasChanges := opts.ChangedSince != "" || opts.Staged || opts.Unstaged || opts.DiffAgainst != ""
+		if len(opts.Targets) == 0 && len(opts.IncludePatterns) == 0 && !opts.FromStdin0 && !opts.FromStdinLine && !hasChanges {
 			fs.Usage()
 			return nil, fmt.Errorf("no target paths provided, and no input from stdin specified")
 		}
@@ -0,0 +1,465 @@
+package options
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/pflag"
+)
+
+// testFlagSet returns a flag set with a few flags of each kind.
+func testFlagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.String("format", "markdown", "")
+	fs.StringSlice("exclude", nil, "")
+	fs.Bool("tree", false, "")
+	fs.Int("max-tokens", 0, "")
+	fs.String("profile", "", "")
+	fs.Bool("version", false, "")
+	return fs
+}
+
+// setupConfigs creates a git repository with the given project
+// configuration, points XDG_CONFIG_HOME at a directory holding the given
+// user configuration, and changes into the repository. Empty
+// configurations are not written. It returns the paths of both files.
+func setupConfigs(t *testing.T, user, projectConfig string) (userPath, projectPath string) {
+	t.Helper()
+
+	root := t.TempDir()
+	if _, err := git.PlainInit(root, false); err != nil {
+		t.Fatalf("failed to init git repo: %v", err)
+	}
+	projectPath = filepath.Join(root, projectConfigName)
+	if projectConfig != "" {
+		if err := os.WriteFile(projectPath, []byte(projectConfig), 0644); err != nil {
+			t.Fatalf("failed to write project config: %v", err)
+		}
+	}
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	userPath = filepath.Join(xdg, "syntex", "config.yaml")
+	if user != "" {
+		if err := os.MkdirAll(filepath.Dir(userPath), 0755); err != nil {
+			t.Fatalf("failed to create user config dir: %v", err)
+		}
+		if err := os.WriteFile(userPath, []byte(user), 0644); err != nil {
+			t.Fatalf("failed to write user config: %v", err)
+		}
+	}
+
+	t.Chdir(root)
+	return userPath, projectPath
+}
+
+func TestParseConfig(t *testing.T) {
+	testCases := []struct {
+		name         string
+		data         string
+		wantErr      string
+		wantSettings map[string][]string
+		wantProfiles map[string]map[string][]string
+	}{
+		{
+			name:         "scalars and lists",
+			data:         "format: json\ntree: true\nmax-tokens: 500\nexclude: [\"*.md\", vendor]\ntargets: [src]\n",
+			wantSettings: map[string][]string{"format": {"json"}, "tree": {"true"}, "max-tokens": {"500"}, "exclude": {"*.md", "vendor"}, "targets": {"src"}},
+			wantProfiles: map[string]map[string][]string{},
+		},
+		{
+			name:         "profiles",
+			data:         "format: json\nprofiles:\n  review:\n    tree: true\n  empty:\n",
+			wantSettings: map[string][]string{"format": {"json"}},
+			wantProfiles: map[string]map[string][]string{"review": {"tree": {"true"}}, "empty": {}},
+		},
+		{name: "invalid yaml", data: "format: [json", wantErr: "failed to parse cfg.yaml"},
+		{name: "unknown option", data: "colour: red\n", wantErr: `cfg.yaml: unknown option "colour"`},
+		{name: "profile is not configurable", data: "profile: review\n", wantErr: `cfg.yaml: unknown option "profile"`},
+		{name: "version is not configurable", data: "version: true\n", wantErr: `cfg.yaml: unknown option "version"`},
+		{name: "unsupported value", data: "format: {a: b}\n", wantErr: `cfg.yaml: option "format": unsupported value`},
+		{name: "profiles not a map", data: "profiles: [review]\n", wantErr: "cfg.yaml: profiles must map profile names to settings"},
+		{name: "profile not a map", data: "profiles:\n  review: true\n", wantErr: `cfg.yaml: profile "review" must map option names to values`},
+		{name: "unknown option in profile", data: "profiles:\n  review:\n    colour: red\n", wantErr: `cfg.yaml: profile "review": unknown option "colour"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := parseConfig("cfg.yaml", []byte(tc.data), testFlagSet())
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("parseConfig() error = %v, want it to contain %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.settings, tc.wantSettings) {
+				t.Errorf("settings = %v, want %v", cfg.settings, tc.wantSettings)
+			}
+			if !reflect.DeepEqual(cfg.profiles, tc.wantProfiles) {
+				t.Errorf("profiles = %v, want %v", cfg.profiles, tc.wantProfiles)
+			}
+		})
+	}
+}
+
+func TestConfigSettings(t *testing.T) {
+	user := &config{
+		path:     "user.yaml",
+		settings: map[string][]string{"format": {"json"}, "tree": {"true"}},
+		profiles: map[string]map[string][]string{
+			"review": {"max-tokens": {"100"}, "exclude": {"*.md"}},
+		},
+	}
+	project := &config{
+		path:     "project.yaml",
+		settings: map[string][]string{"format": {"xml"}},
+		profiles: map[string]map[string][]string{
+			"review": {"max-tokens": {"200"}},
+			"docs":   {"format": {"markdown"}},
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		profile string
+		want    map[string]setting
+		wantErr string
+	}{
+		{
+			name: "later configs override earlier ones",
+			want: map[string]setting{
+				"format": {values: []string{"xml"}, origin: "project.yaml"},
+				"tree":   {values: []string{"true"}, origin: "user.yaml"},
+			},
+		},
+		{
+			name:    "profile merged across configs",
+			profile: "review",
+			want: map[string]setting{
+				"format":     {values: []string{"xml"}, origin: "project.yaml"},
+				"tree":       {values: []string{"true"}, origin: "user.yaml"},
+				"max-tokens": {values: []string{"200"}, origin: "project.yaml (profile review)"},
+				"exclude":    {values: []string{"*.md"}, origin: "user.yaml (profile review)"},
+			},
+		},
+		{
+			name:    "profile overrides defaults",
+			profile: "docs",
+			want: map[string]setting{
+				"format": {values: []string{"markdown"}, origin: "project.yaml (profile docs)"},
+				"tree":   {values: []string{"true"}, origin: "user.yaml"},
+			},
+		},
+		{
+			name:    "undefined profile",
+			profile: "missing",
+			wantErr: `profile "missing" is not defined in any configuration file`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := configSettings([]*config{user, project}, tc.profile)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("configSettings() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("configSettings() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("configSettings() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseFlags_Precedence(t *testing.T) {
+	testCases := []struct {
+		name       string
+		user       string
+		project    string
+		env        map[string]string
+		args       []string
+		wantFormat string
+		wantOrigin string
+	}{
+		{
+			name:       "default",
+			args:       []string{"."},
+			wantFormat: "markdown",
+		},
+		{
+			name:       "user config",
+			user:       "format: xml\n",
+			args:       []string{"."},
+			wantFormat: "xml",
+			wantOrigin: "user",
+		},
+		{
+			name:       "project config over user config",
+			user:       "format: xml\n",
+			project:    "format: json\n",
+			args:       []string{"."},
+			wantFormat: "json",
+			wantOrigin: "project",
+		},
+		{
+			name:       "profile over project config",
+			user:       "profiles:\n  review:\n    format: org\n",
+			project:    "format: json\n",
+			args:       []string{"-p", "review", "."},
+			wantFormat: "org",
+			wantOrigin: "user (profile review)",
+		},
+		{
+			name:       "environment over config",
+			user:       "format: xml\n",
+			project:    "format: json\n",
+			env:        map[string]string{"SYNTEX_FORMAT": "org"},
+			args:       []string{"."},
+			wantFormat: "org",
+			wantOrigin: "environment (SYNTEX_FORMAT)",
+		},
+		{
+			name:       "empty environment variable ignored",
+			project:    "format: json\n",
+			env:        map[string]string{"SYNTEX_FORMAT": ""},
+			args:       []string{"."},
+			wantFormat: "json",
+			wantOrigin: "project",
+		},
+		{
+			name:       "environment selects profile",
+			project:    "profiles:\n  review:\n    format: xml\n",
+			env:        map[string]string{"SYNTEX_PROFILE": "review"},
+			args:       []string{"."},
+			wantFormat: "xml",
+			wantOrigin: "project (profile review)",
+		},
+		{
+			name:       "command line over environment and config",
+			user:       "format: xml\n",
+			project:    "format: json\n",
+			env:        map[string]string{"SYNTEX_FORMAT": "org"},
+			args:       []string{"--format", "jsonl", "."},
+			wantFormat: "jsonl",
+			wantOrigin: "command line",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			userPath, projectPath := setupConfigs(t, tc.user, tc.project)
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+			opts, err := ParseFlags(tc.args, io.Discard)
+			if err != nil {
+				t.Fatalf("ParseFlags() unexpected error: %v", err)
+			}
+			if opts.OutputFormat != tc.wantFormat {
+				t.Errorf("OutputFormat = %q, want %q", opts.OutputFormat, tc.wantFormat)
+			}
+			wantOrigin := strings.NewReplacer("user", userPath, "project", projectPath).Replace(tc.wantOrigin)
+			if got := opts.origins["format"]; got != wantOrigin {
+				t.Errorf("origin of format = %q, want %q", got, wantOrigin)
+			}
+		})
+	}
+}
+
+func TestParseFlags_ConfigTargets(t *testing.T) {
+	testCases := []struct {
+		name        string
+		args        []string
+		wantTargets []string
+	}{
+		{name: "no other input", wantTargets: []string{"src/**"}},
+		{name: "positional arguments", args: []string{"docs"}, wantTargets: []string{"docs"}},
+		{name: "include patterns", args: []string{"--include", "*.md"}, wantTargets: []string{}},
+		{name: "stdin lines", args: []string{"-l"}, wantTargets: []string{}},
+		{name: "stdin nul", args: []string{"-0"}, wantTargets: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setupConfigs(t, "", "targets: [\"src/**\"]\n")
+			opts, err := ParseFlags(tc.args, io.Discard)
+			if err != nil {
+				t.Fatalf("ParseFlags() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(opts.Targets, tc.wantTargets) {
+				t.Errorf("Targets = %#v, want %#v", opts.Targets, tc.wantTargets)
+			}
+		})
+	}
+}
+
+func TestParseFlags_ConfigPaths(t *testing.T) {
+	setupConfigs(t, "", "targets: [\"src/**\", docs/, /abs]\nignore-file: .extraignore\n")
+	if err := os.Mkdir("pkg", 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	t.Chdir("pkg")
+
+	opts, err := ParseFlags(nil, io.Discard)
+	if err != nil {
+		t.Fatalf("ParseFlags() unexpected error: %v", err)
+	}
+	wantTargets := []string{filepath.Join("..", "src", "**"), filepath.Join("..", "docs") + string(filepath.Separator), "/abs"}
+	if !reflect.DeepEqual(opts.Targets, wantTargets) {
+		t.Errorf("Targets = %#v, want %#v", opts.Targets, wantTargets)
+	}
+	if want := []string{filepath.Join("..", ".extraignore")}; !reflect.DeepEqual(opts.IgnoreFiles, want) {
+		t.Errorf("IgnoreFiles = %#v, want %#v", opts.IgnoreFiles, want)
+	}
+}
+
+func TestParseFlags_ProjectConfigUntrusted(t *testing.T) {
+	testCases := []struct {
+		name    string
+		user    string
+		project string
+		wantErr string
+	}{
+		{name: "output in user config", user: "output: out.md\nno-redact: true\nclipboard: true\n"},
+		{name: "output", project: "output: out.md\n", wantErr: `option "output" cannot be set in a project configuration`},
+		{name: "no-redact", project: "no-redact: true\n", wantErr: `option "no-redact" cannot be set in a project configuration`},
+		{name: "clipboard", project: "clipboard: true\n", wantErr: `option "clipboard" cannot be set in a project configuration`},
+		{name: "profile", project: "profiles:\n  share:\n    output: out.md\n", wantErr: `profile "share": option "output" cannot be set in a project configuration`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setupConfigs(t, tc.user, tc.project)
+			_, err := ParseFlags([]string{"--dry-run", "."}, io.Discard)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseFlags() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("ParseFlags() error = %v, want it to contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestOptions_WriteSettings(t *testing.T) {
+	userPath, projectPath := setupConfigs(t, "tree: true\n", "format: json\ntargets: [src]\n")
+	t.Setenv("SYNTEX_MAX_TOKENS", "500")
+
+	opts, err := ParseFlags([]string{"--print-config", "--hidden"}, io.Discard)
+	if err != nil {
+		t.Fatalf("ParseFlags() unexpected error: %v", err)
+	}
+	var sb strings.Builder
+	if err := opts.WriteSettings(&sb); err != nil {
+		t.Fatalf("WriteSettings() unexpected error: %v", err)
+	}
+
+	rows := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n") {
+		fields := strings.Fields(line)
+		rows[fields[0]] = fields[1:]
+	}
+	wantRows := map[string][]string{
+		"hidden":     {"true", "command", "line"},
+		"max-tokens": {"500", "environment", "(SYNTEX_MAX_TOKENS)"},
+		"format":     {"json", projectPath},
+		"tree":       {"true", userPath},
+		"jobs":       {"0", "default"},
+		"targets":    {"[src]", projectPath},
+	}
+	for name, want := range wantRows {
+		if got := rows[name]; !reflect.DeepEqual(got, want) {
+			t.Errorf("row %s = %q, want %q", name, got, want)
+		}
+	}
+	for _, name := range []string{"print-config", "version"} {
+		if _, ok := rows[name]; ok {
+			t.Errorf("settings unexpectedly list %s", name)
+		}
+	}
+}
+
+func TestParseFlags_ClipboardMax(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "default", args: []string{"-c", "."}, want: defaultClipboardMax},
+		{name: "size with suffix", args: []string{"-c", "--clipboard-max", "20m", "."}, want: 20_000_000},
+		{name: "no limit", args: []string{"-c", "--clipboard-max", "0", "."}, want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setupConfigs(t, "", "")
+			opts, err := ParseFlags(tc.args, io.Discard)
+			if err != nil {
+				t.Fatalf("ParseFlags() unexpected error: %v", err)
+			}
+			if opts.ClipboardMax != tc.want {
+				t.Errorf("ClipboardMax = %d, want %d", opts.ClipboardMax, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{input: "0", want: 0},
+		{input: "0k", want: 0},
+		{input: "4096", want: 4096},
+		{input: "200k", want: 200000},
+		{input: "200K", want: 200000},
+		{input: "1.5m", want: 1500000},
+		{input: "2M", want: 2000000},
+		{input: " 10k ", want: 10000},
+		{input: "", wantErr: true},
+		{input: "k", wantErr: true},
+		{input: "-1", wantErr: true},
+		{input: "10g", wantErr: true},
+		{input: "nan", wantErr: true},
+		{input: "inf", wantErr: true},
+		{input: "1e30", wantErr: true},
+		{input: "9223372036854775807", wantErr: true},
+		{input: "10000000000000m", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseSize(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseSize(%q) = %d, want an error", tc.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSize(%q) unexpected error: %v", tc.input, err)
+			}
+			if got != tc.want {
+				t.Errorf("parseSize(%q) = %d, want %d", tc.input, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package options
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sizeValue is a pflag.Value for sizes with an optional decimal suffix,
+// such as "200k" or "1.5m".
+type sizeValue struct {
+	target *int
+}
+
+// newSizeValue binds a sizeValue to target.
+func newSizeValue(target *int) *sizeValue {
+	return &sizeValue{target: target}
+}
+
+// String returns the current value.
+func (v *sizeValue) String() string {
+	if v.target == nil || *v.target == 0 {
+		return ""
+	}
+	return strconv.Itoa(*v.target)
+}
+
+// Set parses a size such as "4096", "200k" or "2m".
+func (v *sizeValue) Set(s string) error {
+	size, err := parseSize(s)
+	if err != nil {
+		return err
+	}
+	*v.target = size
+	return nil
+}
+
+// Type returns the type name shown in the usage message.
+func (v *sizeValue) Type() string {
+	return "size"
+}
+
+// parseSize parses a non-negative number with an optional k (thousand) or
+// m (million) suffix.
+func parseSize(s string) (int, error) {
+	trimmed := strings.ToLower(strings.TrimSpace(s))
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(trimmed, "k"):
+		multiplier, trimmed = 1e3, strings.TrimSuffix(trimmed, "k")
+	case strings.HasSuffix(trimmed, "m"):
+		multiplier, trimmed = 1e6, strings.TrimSuffix(trimmed, "m")
+	}
+
+	n, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || !(n >= 0) {
+		return 0, fmt.Errorf("invalid size %q: expected a number with an optional k or m suffix", s)
+	}
+	size := n * multiplier
+	if size >= math.MaxInt {
+		return 0, fmt.Errorf("invalid size %q: too large", s)
+	}
+	return int(size), nil
+}
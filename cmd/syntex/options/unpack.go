@@ -0,0 +1,67 @@
+package options
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// UnpackOptions holds the parsed command-line flags of the unpack subcommand.
+type UnpackOptions struct {
+	Dir    string
+	Format string
+	DryRun bool
+	Force  bool
+
+	// Input is the pack file to read, or "" or "-" for stdin.
+	Input string
+}
+
+// ParseUnpackFlags parses the arguments of the unpack subcommand.
+func ParseUnpackFlags(args []string, stderr io.Writer) (*UnpackOptions, error) {
+	opts := &UnpackOptions{}
+	fs := pflag.NewFlagSet("syntex unpack", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fs.StringVarP(&opts.Dir, "dir", "C", ".", "Write the files below this directory, creating it if needed.")
+	fs.StringVarP(&opts.Format, "format", "f", "", "Format of the pack (markdown, md, org). Detected from the file name or content by default.")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "Print the files that would be written without writing them.")
+	fs.BoolVar(&opts.Force, "force", false, "Overwrite existing files.")
+
+	fs.Usage = func() {
+		output := fs.Output()
+		progName := filepath.Base(os.Args[0])
+		var b strings.Builder
+
+		fmt.Fprintf(&b, "Write the files of a Markdown or Org pack back to disk.\n\n")
+		fmt.Fprintf(&b, "Usage:\n  %s unpack [OPTIONS] [pack]\n\n", progName)
+		fmt.Fprintf(&b, "Arguments:\n")
+		fmt.Fprintf(&b, "  [pack]   The packed document to read. If omitted or \"-\", it is read from stdin.\n")
+		fmt.Fprintf(&b, "             Blocks holding a line range, a symbol or a diff are skipped.\n\n")
+		fmt.Fprintf(&b, "Options:\n")
+
+		fmt.Fprint(output, b.String())
+		fmt.Fprint(output, fs.FlagUsages())
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	switch opts.Format {
+	case "", "markdown", "md", "org":
+	default:
+		return nil, fmt.Errorf("unsupported pack format %q (supported: markdown, md, org)", opts.Format)
+	}
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return nil, fmt.Errorf("unpack reads a single pack, got %d arguments", fs.NArg())
+	}
+	opts.Input = fs.Arg(0)
+	return opts, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jbwfu/syntex/cmd/syntex/options"
+	"github.com/jbwfu/syntex/internal/unpack"
+)
+
+// runUnpack executes the unpack subcommand, writing the files of a pack
+// read from a file or stdin back to disk.
+func runUnpack(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	opts, err := options.ParseUnpackFlags(args, stderr)
+	if err != nil {
+		return err
+	}
+
+	var content []byte
+	if opts.Input == "" || opts.Input == "-" {
+		content, err = io.ReadAll(stdin)
+	} else {
+		content, err = os.ReadFile(opts.Input)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read pack: %w", err)
+	}
+
+	format := opts.Format
+	if format == "" {
+		format = unpack.DetectFormat(opts.Input, content)
+	}
+	files, skipped, err := unpack.Parse(content, format)
+	if err != nil {
+		return fmt.Errorf("failed to parse pack: %w", err)
+	}
+	for _, name := range skipped {
+		fmt.Fprintf(stderr, "warning: skipping partial block %s\n", name)
+	}
+
+	results, err := unpack.Write(opts.Dir, files, unpack.Options{DryRun: opts.DryRun, Overwrite: opts.Force})
+	if err != nil {
+		if errors.Is(err, unpack.ErrExists) {
+			return fmt.Errorf("%w (use --force to overwrite)", err)
+		}
+		return err
+	}
+
+	if opts.DryRun {
+		return printUnpackDryRun(stdout, results, opts.Dir)
+	}
+	fmt.Fprintf(stderr, "Unpacked %d files into %s\n", len(results), opts.Dir)
+	return nil
+}
+
+// printUnpackDryRun displays the files an unpack would write.
+func printUnpackDryRun(w io.Writer, results []unpack.Result, dir string) error {
+	if len(results) == 0 {
+		fmt.Fprintln(w, "[Dry Run] No files to be unpacked.")
+		return nil
+	}
+
+	fmt.Fprintf(w, "[Dry Run] Planning to unpack files into %s:\n", dir)
+	overwritten := 0
+	for _, result := range results {
+		action := "create   "
+		if result.Exists {
+			action = "overwrite"
+			overwritten++
+		}
+		fmt.Fprintf(w, "%s  %s\n", action, result.Path)
+	}
+
+	fmt.Fprintf(w, "\n[Dry Run] Total: %d files, %d overwritten\n", len(results), overwritten)
+	return nil
+}
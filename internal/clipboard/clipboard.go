@@ -2,6 +2,7 @@ package clipboard
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 
 	"github.com/atotto/clipboard"
@@ -13,11 +14,19 @@ type clipboardWriterFunc func(text string) error
 // defaultClipboardWriter is the actual function that writes to the system clipboard.
 var defaultClipboardWriter clipboardWriterFunc = clipboard.WriteAll
 
+// ErrTooLarge is returned by Write when the content would exceed the size
+// limit of a Writer.
+var ErrTooLarge = errors.New("clipboard: content exceeds the size limit")
+
 // Writer implements io.WriteCloser. It buffers all data written to it
 // until its Close method is called, at which point the buffered content
 // is written to the system clipboard using the configured writer function.
 type Writer struct {
 	buf bytes.Buffer
+	// limit is the maximum number of bytes buffered, or zero for no limit.
+	limit int
+	// overflowed is set once a write exceeded the limit.
+	overflowed bool
 	// writerFunc is the function used to write to the clipboard.
 	// It can be overridden for testing.
 	writerFunc clipboardWriterFunc
@@ -32,17 +41,35 @@ func NewWriter() *Writer {
 	}
 }
 
+// NewLimitedWriter creates a clipboard Writer that buffers at most limit
+// bytes. A write that would exceed the limit fails with ErrTooLarge, and
+// nothing is copied to the clipboard on Close. A limit of zero means no limit.
+func NewLimitedWriter(limit int) *Writer {
+	w := NewWriter()
+	w.limit = limit
+	return w
+}
+
 // Write appends the given byte slice to the internal buffer.
 // It implements the io.Writer interface.
 func (cw *Writer) Write(p []byte) (n int, err error) {
+	if !cw.overflowed && cw.limit > 0 && cw.buf.Len()+len(p) > cw.limit {
+		// Release the buffered content early, it will never be copied.
+		cw.overflowed = true
+		cw.buf = bytes.Buffer{}
+	}
+	if cw.overflowed {
+		return 0, fmt.Errorf("%w of %d bytes", ErrTooLarge, cw.limit)
+	}
 	return cw.buf.Write(p)
 }
 
 // Close writes all buffered data to the system clipboard using the configured writerFunc.
 // It implements the io.Closer interface.
-// After writing, the internal buffer is reset.
+// After writing, the internal buffer is reset. Nothing is written if the
+// size limit was exceeded.
 func (cw *Writer) Close() error {
-	if cw.buf.Len() == 0 {
+	if cw.overflowed || cw.buf.Len() == 0 {
 		return nil
 	}
 	text := cw.buf.String()
@@ -0,0 +1,58 @@
+package clipboard
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLimitedWriter(t *testing.T) {
+	testCases := []struct {
+		name    string
+		limit   int
+		writes  []string
+		wantErr bool
+		want    string
+		copied  bool
+	}{
+		{name: "within the limit", limit: 10, writes: []string{"hello", "world"}, want: "helloworld", copied: true},
+		{name: "over the limit", limit: 10, writes: []string{"hello", "world!"}, wantErr: true},
+		{name: "writes after the limit was exceeded", limit: 10, writes: []string{"hello world", "!"}, wantErr: true},
+		{name: "no limit", limit: 0, writes: []string{strings.Repeat("x", 1<<20)}, want: strings.Repeat("x", 1<<20), copied: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewLimitedWriter(tc.limit)
+			var copied *string
+			w.writerFunc = func(text string) error {
+				copied = &text
+				return nil
+			}
+
+			var err error
+			for _, s := range tc.writes {
+				if _, werr := io.WriteString(w, s); werr != nil {
+					err = werr
+				}
+			}
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("Write() error = %v, want error: %v", err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrTooLarge) {
+				t.Errorf("Write() error = %v, want ErrTooLarge", err)
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() returned an unexpected error: %v", err)
+			}
+			if (copied != nil) != tc.copied {
+				t.Fatalf("copied to the clipboard: %v, want %v", copied != nil, tc.copied)
+			}
+			if copied != nil && *copied != tc.want {
+				t.Errorf("copied %d bytes, want %d", len(*copied), len(tc.want))
+			}
+		})
+	}
+}
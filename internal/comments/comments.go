@@ -0,0 +1,370 @@
+// Package comments removes comments from source code without altering its
+// string literals.
+package comments
+
+import "bytes"
+
+// syntax describes the comment and string literal syntax of a language.
+type syntax struct {
+	// line starts a comment that runs to the end of the line.
+	line string
+	// lineAfterSpace restricts line comments to the start of a line or
+	// after whitespace, as in shell where "$#" is not a comment.
+	lineAfterSpace bool
+	// blockStart and blockEnd delimit block comments.
+	blockStart, blockEnd string
+	// quotes lists the characters that delimit single-line string literals.
+	quotes string
+	// multilineQuotes lists the characters that delimit string literals
+	// which may span lines, such as Go raw strings.
+	multilineQuotes string
+	// rawQuotes lists the quote characters whose literals have no escapes.
+	rawQuotes string
+	// tripleQuotes enables Python-style """ and ''' literals.
+	tripleQuotes bool
+	// directives lists line comment prefixes that are compiler directives
+	// rather than comments when they start a line, such as "//go:build".
+	directives []string
+	// regexLiterals enables JavaScript-style /regex/ literals.
+	regexLiterals bool
+}
+
+var (
+	cFamily = syntax{line: "//", blockStart: "/*", blockEnd: "*/", quotes: `"'`}
+	goLang  = syntax{line: "//", blockStart: "/*", blockEnd: "*/", quotes: `"'`, multilineQuotes: "`", rawQuotes: "`", directives: []string{"//go:", "// +build"}}
+	jsLang  = syntax{line: "//", blockStart: "/*", blockEnd: "*/", quotes: `"'`, multilineQuotes: "`", regexLiterals: true}
+	css     = syntax{blockStart: "/*", blockEnd: "*/", quotes: `"'`}
+	hash    = syntax{line: "#", lineAfterSpace: true, quotes: `"'`}
+	shell   = syntax{line: "#", lineAfterSpace: true, quotes: `"'`, rawQuotes: "'"}
+	python  = syntax{line: "#", lineAfterSpace: true, quotes: `"'`, tripleQuotes: true}
+	yaml    = syntax{line: "#", lineAfterSpace: true, quotes: `"'`, rawQuotes: "'"}
+)
+
+// languages maps the language identifiers of language.Detector to their syntax.
+var languages = map[string]syntax{
+	"c":             cFamily,
+	"cpp":           cFamily,
+	"csharp":        cFamily,
+	"dart":          cFamily,
+	"groovy":        cFamily,
+	"java":          cFamily,
+	"kotlin":        cFamily,
+	"objective-c":   cFamily,
+	"objective-c++": cFamily,
+	"php":           cFamily,
+	"protobuf":      cFamily,
+	"rust":          cFamily,
+	"scala":         cFamily,
+	"swift":         cFamily,
+	"go":            goLang,
+	"javascript":    jsLang,
+	"typescript":    jsLang,
+	"tsx":           jsLang,
+	"css":           css,
+	"less":          cFamily,
+	"scss":          cFamily,
+	"cmake":         hash,
+	"dockerfile":    hash,
+	"elixir":        hash,
+	"makefile":      hash,
+	"perl":          hash,
+	"r":             hash,
+	"ruby":          hash,
+	"toml":          hash,
+	"shell":         shell,
+	"python":        python,
+	"yaml":          yaml,
+}
+
+// Strip removes the comments from src, written in the given language, and
+// collapses runs of blank lines into one. Lines left empty by a removed
+// comment are dropped, and a leading "#!" line is kept. String literals,
+// including any blank lines inside them, are preserved. If the language is
+// not supported, src is returned unchanged.
+func Strip(language string, src []byte) []byte {
+	syn, ok := languages[language]
+	if !ok {
+		return src
+	}
+	s := &stripper{syn: syn, src: src, out: make([]byte, 0, len(src))}
+	return s.run()
+}
+
+// stripper holds the state of a single Strip call.
+type stripper struct {
+	syn syntax
+	src []byte
+	out []byte
+	pos int
+
+	// lineStart is the offset in out of the line being written.
+	lineStart int
+	// commented is true if a comment was removed from the current line.
+	commented bool
+	// lastLine is the offset in out of the last line written.
+	lastLine int
+	// blank is true if the last line written was blank.
+	blank bool
+}
+
+// run scans src once, copying code and string literals to out.
+func (s *stripper) run() []byte {
+	if s.syn.line == "#" && bytes.HasPrefix(s.src, []byte("#!")) {
+		s.copyLine()
+	}
+
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\n':
+			s.pos++
+			s.endLine(true)
+		case s.isDirective():
+			s.keepLine()
+		case s.syn.blockStart != "" && s.hasPrefix(s.syn.blockStart):
+			s.skipBlock()
+		case s.syn.line != "" && s.hasPrefix(s.syn.line) && s.lineCommentAllowed():
+			s.skipLine()
+		case s.syn.regexLiterals && c == '/' && s.regexAllowed():
+			s.copyRegex()
+		case s.syn.tripleQuotes && (s.hasPrefix(`"""`) || s.hasPrefix(`'''`)):
+			s.copyTriple()
+		case bytes.IndexByte([]byte(s.syn.multilineQuotes), c) >= 0:
+			s.copyString(c, true)
+		case bytes.IndexByte([]byte(s.syn.quotes), c) >= 0:
+			s.copyString(c, false)
+		default:
+			s.out = append(s.out, c)
+			s.pos++
+		}
+	}
+	return s.finish()
+}
+
+// hasPrefix reports whether src continues with prefix at the current position.
+func (s *stripper) hasPrefix(prefix string) bool {
+	return bytes.HasPrefix(s.src[s.pos:], []byte(prefix))
+}
+
+// lineCommentAllowed reports whether a line comment may start at the current position.
+func (s *stripper) lineCommentAllowed() bool {
+	if !s.syn.lineAfterSpace || s.pos == 0 {
+		return true
+	}
+	prev := s.src[s.pos-1]
+	return prev == ' ' || prev == '\t' || prev == '\n' || prev == '\r'
+}
+
+// isDirective reports whether a directive starts a line at the current position.
+func (s *stripper) isDirective() bool {
+	if s.pos > 0 && s.src[s.pos-1] != '\n' {
+		return false
+	}
+	for _, prefix := range s.syn.directives {
+		if s.hasPrefix(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepLine copies the current line verbatim as a line of code.
+func (s *stripper) keepLine() {
+	s.lastLine, s.blank = s.lineStart, false
+	s.copyLine()
+}
+
+// regexKeywords lists the keywords after which a slash starts a regular
+// expression rather than a division.
+var regexKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "case": true,
+	"do": true, "else": true, "yield": true, "await": true,
+}
+
+// regexAllowed reports whether a slash at the current position starts a
+// regular expression literal, judging by the code written before it: an
+// operator, an opening bracket, a keyword or nothing at all.
+func (s *stripper) regexAllowed() bool {
+	code := bytes.TrimRight(s.out, " \t\r\n")
+	if len(code) == 0 {
+		return true
+	}
+	last := code[len(code)-1]
+	if bytes.IndexByte([]byte("(,=:[!&|?{};+-*%<>~^"), last) >= 0 {
+		return true
+	}
+	start := len(code)
+	for start > 0 && isIdentByte(code[start-1]) {
+		start--
+	}
+	return regexKeywords[string(code[start:])]
+}
+
+// isIdentByte reports whether c may be part of an identifier.
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// copyRegex copies a regular expression literal and its flags. Slashes
+// inside a character class or escaped do not end it. A literal that is
+// not closed on its line is not a literal at all, so only the slash is copied.
+func (s *stripper) copyRegex() {
+	inClass := false
+	for i := s.pos + 1; i < len(s.src); i++ {
+		switch c := s.src[i]; {
+		case c == '\\':
+			i++
+		case c == '\n':
+			s.out = append(s.out, '/')
+			s.pos++
+			return
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			end := i + 1
+			for end < len(s.src) && isIdentByte(s.src[end]) {
+				end++
+			}
+			s.copyLiteral(end)
+			return
+		}
+	}
+	s.out = append(s.out, '/')
+	s.pos++
+}
+
+// copyLine copies the rest of the current line, including its newline, verbatim.
+func (s *stripper) copyLine() {
+	end := bytes.IndexByte(s.src[s.pos:], '\n')
+	if end < 0 {
+		end = len(s.src) - s.pos - 1
+	}
+	s.out = append(s.out, s.src[s.pos:s.pos+end+1]...)
+	s.pos += end + 1
+	s.lineStart = len(s.out)
+}
+
+// skipLine skips a line comment up to, but not including, its newline.
+func (s *stripper) skipLine() {
+	end := bytes.IndexByte(s.src[s.pos:], '\n')
+	if end < 0 {
+		end = len(s.src) - s.pos
+	}
+	// Keep a carriage return so that CRLF line endings survive.
+	if end > 0 && s.src[s.pos+end-1] == '\r' {
+		end--
+	}
+	s.pos += end
+	s.commented = true
+}
+
+// skipBlock skips a block comment. An unterminated comment runs to the end of src.
+func (s *stripper) skipBlock() {
+	rest := s.src[s.pos+len(s.syn.blockStart):]
+	end := bytes.Index(rest, []byte(s.syn.blockEnd))
+	if end < 0 {
+		s.pos = len(s.src)
+	} else {
+		s.pos += len(s.syn.blockStart) + end + len(s.syn.blockEnd)
+	}
+	s.commented = true
+}
+
+// copyString copies a string literal delimited by quote. A single-line
+// literal that is not closed on its line is not a literal at all, like an
+// apostrophe in a Rust lifetime or a YAML plain scalar, so only the quote
+// itself is copied.
+func (s *stripper) copyString(quote byte, multiline bool) {
+	raw := bytes.IndexByte([]byte(s.syn.rawQuotes), quote) >= 0
+	for i := s.pos + 1; i < len(s.src); i++ {
+		switch c := s.src[i]; {
+		case c == '\\' && !raw:
+			i++
+		case c == quote:
+			s.copyLiteral(i + 1)
+			return
+		case c == '\n' && !multiline:
+			s.out = append(s.out, quote)
+			s.pos++
+			return
+		}
+	}
+	if multiline {
+		s.copyLiteral(len(s.src))
+		return
+	}
+	s.out = append(s.out, quote)
+	s.pos++
+}
+
+// copyTriple copies a Python triple-quoted string literal.
+func (s *stripper) copyTriple() {
+	delim := s.src[s.pos : s.pos+3]
+	for i := s.pos + 3; i < len(s.src); i++ {
+		if s.src[i] == '\\' {
+			i++
+			continue
+		}
+		if bytes.HasPrefix(s.src[i:], delim) {
+			s.copyLiteral(i + 3)
+			return
+		}
+	}
+	s.copyLiteral(len(s.src))
+}
+
+// copyLiteral copies src up to end verbatim. If the literal spans lines,
+// the line being written continues after its last newline.
+func (s *stripper) copyLiteral(end int) {
+	end = min(end, len(s.src))
+	literal := s.src[s.pos:end]
+	s.out = append(s.out, literal...)
+	s.pos = end
+	if i := bytes.LastIndexByte(literal, '\n'); i >= 0 {
+		s.lineStart = len(s.out) - len(literal) + i + 1
+		s.commented, s.blank = false, false
+	}
+}
+
+// endLine finishes the line being written, whose newline, if any, has
+// already been consumed from src. Trailing whitespace left by a removed
+// comment is trimmed, lines emptied by a comment are dropped and blank
+// lines after another blank line, or at the start, are dropped.
+func (s *stripper) endLine(newline bool) {
+	line := s.out[s.lineStart:]
+	cr := len(line) > 0 && line[len(line)-1] == '\r'
+	if s.commented {
+		line = bytes.TrimRight(line, " \t\r")
+	}
+
+	isBlank := len(bytes.TrimSpace(line)) == 0
+	if isBlank && (s.commented || s.blank || s.lineStart == 0) {
+		s.out = s.out[:s.lineStart]
+	} else {
+		s.out = s.out[:s.lineStart+len(line)]
+		if cr && s.commented {
+			s.out = append(s.out, '\r')
+		}
+		if newline {
+			s.out = append(s.out, '\n')
+		}
+		s.lastLine, s.blank = s.lineStart, isBlank
+	}
+
+	s.lineStart = len(s.out)
+	s.commented = false
+}
+
+// finish ends the last line of src and drops a trailing blank line.
+func (s *stripper) finish() []byte {
+	if s.lineStart < len(s.out) || s.commented {
+		s.endLine(false)
+	}
+	if s.blank {
+		s.out = s.out[:s.lastLine]
+	}
+	return s.out
+}
@@ -0,0 +1,104 @@
+package comments
+
+import "testing"
+
+func TestStrip(t *testing.T) {
+	testCases := []struct {
+		name     string
+		language string
+		src      string
+		want     string
+	}{
+		{
+			name:     "go license header, doc and trailing comments",
+			language: "go",
+			src: "/*\n * Copyright 2024 Example.\n */\n\n// Package main is a demo.\npackage main\n\n\n" +
+				"func main() { // entry point\n\t// x := 1\n\tprintln(\"// not a comment\") /* done */\n}\n",
+			want: "package main\n\nfunc main() {\n\tprintln(\"// not a comment\")\n}\n",
+		},
+		{
+			name:     "go raw strings keep comments and blank lines",
+			language: "go",
+			src:      "var s = `a // b\n\n\n/* c */`\n// gone\nvar r = '\\''\n",
+			want:     "var s = `a // b\n\n\n/* c */`\nvar r = '\\''\n",
+		},
+		{
+			name:     "go directives are kept",
+			language: "go",
+			src: "//go:build linux\n// +build linux\n\n// Package x is a demo.\npackage x\n\nimport _ \"embed\"\n\n" +
+				"// a is embedded.\n//go:embed a.txt\nvar a string // c\n\n//go:generate stringer -type=T\n",
+			want: "//go:build linux\n// +build linux\n\npackage x\n\nimport _ \"embed\"\n\n" +
+				"//go:embed a.txt\nvar a string\n\n//go:generate stringer -type=T\n",
+		},
+		{
+			name:     "javascript regex literals are not comments",
+			language: "javascript",
+			src: "const r = /\\/\\//g; // c\nif (/[/*]/.test(s)) x = 1; /* d */\n" +
+				"function f() { return /a\\/\\/b/.test(x) }\nconst q = a / b / c; // e\n",
+			want: "const r = /\\/\\//g;\nif (/[/*]/.test(s)) x = 1;\n" +
+				"function f() { return /a\\/\\/b/.test(x) }\nconst q = a / b / c;\n",
+		},
+		{
+			name:     "escaped quotes do not end strings",
+			language: "javascript",
+			src:      "const s = \"a \\\" // b\"; // c\n",
+			want:     "const s = \"a \\\" // b\";\n",
+		},
+		{
+			name:     "unclosed quote is not a string",
+			language: "rust",
+			src:      "fn f<'a>(x: &str) {} // c\n",
+			want:     "fn f<'a>(x: &str) {}\n",
+		},
+		{
+			name:     "css has no line comments",
+			language: "css",
+			src:      "a { background: url(//example.com/x.png); } /* c */\n",
+			want:     "a { background: url(//example.com/x.png); }\n",
+		},
+		{
+			name:     "shell keeps shebang and parameter expansions",
+			language: "shell",
+			src:      "#!/bin/sh\n# usage: demo\necho $# ${#1} '#' \"#\" # count\n",
+			want:     "#!/bin/sh\necho $# ${#1} '#' \"#\"\n",
+		},
+		{
+			name:     "python docstrings are strings",
+			language: "python",
+			src:      "def f():\n    \"\"\"Doc # text.\n\n    More.\n    \"\"\"\n    # comment\n    return '#'  # trailing\n",
+			want:     "def f():\n    \"\"\"Doc # text.\n\n    More.\n    \"\"\"\n    return '#'\n",
+		},
+		{
+			name:     "yaml apostrophes in plain scalars",
+			language: "yaml",
+			src:      "# config\nname: it's here # note\nurl: http://x/#anchor\n",
+			want:     "name: it's here\nurl: http://x/#anchor\n",
+		},
+		{
+			name:     "crlf line endings are preserved",
+			language: "c",
+			src:      "int a; // x\r\n\r\n\r\n// y\r\nint b;\r\n",
+			want:     "int a;\r\n\r\nint b;\r\n",
+		},
+		{
+			name:     "trailing blank lines are removed",
+			language: "go",
+			src:      "package a\n\n// end\n\n",
+			want:     "package a\n",
+		},
+		{
+			name:     "unsupported languages are unchanged",
+			language: "markdown",
+			src:      "# Title\n\n\n<!-- c -->\n",
+			want:     "# Title\n\n\n<!-- c -->\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := string(Strip(tc.language, []byte(tc.src))); got != tc.want {
+				t.Errorf("Strip() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
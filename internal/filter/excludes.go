@@ -0,0 +1,183 @@
+package filter
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// excludePatterns returns the ignore patterns git applies to the repository
+// at root besides its .gitignore files, in ascending order of priority: the
+// global excludes file and the repository's info/exclude file.
+func excludePatterns(root string) ([]gitignore.Pattern, error) {
+	dotGit := filepath.Join(root, ".git")
+	commonDir := commonGitDir(dotGit)
+
+	patterns, err := globalExcludePatterns(commonDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// gitignore.ReadPatterns already reads info/exclude from a .git directory,
+	// but linked worktrees keep it in the common git directory.
+	if info, err := os.Stat(dotGit); err == nil && !info.IsDir() {
+		infoExclude, err := readPatternFile(filepath.Join(commonDir, "info", "exclude"), nil)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, infoExclude...)
+	}
+	return patterns, nil
+}
+
+// commonGitDir returns the git directory holding the configuration and
+// info/exclude of the repository whose .git entry is at dotGit. For linked
+// worktrees and submodules, .git is a file pointing to the git directory,
+// which may in turn point to a common directory shared by all worktrees.
+func commonGitDir(dotGit string) string {
+	content, err := os.ReadFile(dotGit)
+	if err != nil {
+		return dotGit
+	}
+	dir, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+	if !ok {
+		return dotGit
+	}
+	gitDir := resolvePath(filepath.Dir(dotGit), strings.TrimSpace(dir))
+
+	common, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	return resolvePath(gitDir, strings.TrimSpace(string(common)))
+}
+
+// globalExcludePatterns returns the patterns of the file named by
+// core.excludesFile in the configuration with the highest priority that
+// sets it: the repository, user or system configuration. Without it, git
+// reads $XDG_CONFIG_HOME/git/ignore. The gitignore loaders do not tell an
+// unset key from a missing or empty file, so a user or system configuration
+// naming one is passed over. As in git, GIT_CONFIG_GLOBAL and
+// GIT_CONFIG_SYSTEM name other user and system configuration files, and
+// GIT_CONFIG_NOSYSTEM skips the system configuration.
+func globalExcludePatterns(commonDir string) ([]gitignore.Pattern, error) {
+	if path := repositoryExcludesFile(commonDir); path != "" {
+		return readPatternFile(path, nil)
+	}
+
+	root := osfs.New("/")
+	home, _ := os.UserHomeDir()
+	if home != "" {
+		global := gitConfigFS{Filesystem: root, path: filepath.Join(home, ".gitconfig"), env: "GIT_CONFIG_GLOBAL"}
+		patterns, err := gitignore.LoadGlobalPatterns(global)
+		if err != nil || len(patterns) > 0 {
+			return patterns, err
+		}
+	}
+	if os.Getenv("GIT_CONFIG_NOSYSTEM") == "" {
+		system := gitConfigFS{Filesystem: root, path: systemConfigFile, env: "GIT_CONFIG_SYSTEM"}
+		patterns, err := gitignore.LoadSystemPatterns(system)
+		if err != nil || len(patterns) > 0 {
+			return patterns, err
+		}
+	}
+
+	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfig == "" {
+		if home == "" {
+			return nil, nil
+		}
+		xdgConfig = filepath.Join(home, ".config")
+	}
+	return readPatternFile(filepath.Join(xdgConfig, "git", "ignore"), nil)
+}
+
+// systemConfigFile is the system-wide git configuration read by
+// gitignore.LoadSystemPatterns.
+const systemConfigFile = "/etc/gitconfig"
+
+// gitConfigFS is a filesystem that opens the file named by the environment
+// variable env, if it is set, in place of the git configuration at path.
+// It points the gitignore loaders, which read fixed paths, at the
+// configuration files git itself would read.
+type gitConfigFS struct {
+	billy.Filesystem
+	path string
+	env  string
+}
+
+// Open opens the named file, or the configuration file named by the
+// environment in place of fs.path.
+func (fs gitConfigFS) Open(name string) (billy.File, error) {
+	if override := os.Getenv(fs.env); override != "" && name == fs.path {
+		path, err := filepath.Abs(override)
+		if err != nil {
+			return nil, err
+		}
+		name = path
+	}
+	return fs.Filesystem.Open(name)
+}
+
+// repositoryExcludesFile returns the path set by core.excludesFile in the
+// configuration of the repository in commonDir, or "" if it is not set.
+func repositoryExcludesFile(commonDir string) string {
+	f, err := os.Open(filepath.Join(commonDir, "config"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	cfg, err := gitconfig.ReadConfig(f)
+	if err != nil {
+		return ""
+	}
+	path := cfg.Raw.Section("core").Options.Get("excludesfile")
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// readPatternFile parses a file in gitignore syntax whose patterns apply
+// below domain, the slash-separated components of a directory relative to
+// the matcher's root. A missing file, or an empty path, has no patterns.
+func readPatternFile(path string, domain []string) ([]gitignore.Pattern, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") && strings.TrimSpace(line) != "" {
+			patterns = append(patterns, gitignore.ParsePattern(line, domain))
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// resolvePath joins a relative path to base.
+func resolvePath(base, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(base, path)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 
@@ -18,13 +19,21 @@ type gitignoreFilter struct {
 	matcher gitignore.Matcher
 }
 
-// newGitignoreFilter creates a filter by reading .gitignore patterns from a given root path.
+// newGitignoreFilter creates a filter for the repository at rootPath from
+// the same sources as git status, from lowest to highest priority: the
+// global excludes file, info/exclude and the .gitignore files.
 func newGitignoreFilter(rootPath string) (*gitignoreFilter, error) {
+	patterns, err := excludePatterns(rootPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read exclude patterns: %w", err)
+	}
+
 	fs := osfs.New(rootPath)
-	patterns, err := gitignore.ReadPatterns(fs, nil)
+	gitignorePatterns, err := gitignore.ReadPatterns(fs, nil)
 	if err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("could not read gitignore patterns: %w", err)
 	}
+	patterns = append(patterns, gitignorePatterns...)
 
 	// Always ignore the .git directory itself.
 	patterns = append(patterns, gitignore.ParsePattern(".git", nil))
@@ -37,36 +46,87 @@ func (f *gitignoreFilter) isIgnored(pathRelativeToRoot string, isDir bool) bool
 	return f.matcher.Match(components, isDir)
 }
 
+// syntexignoreFile is the name of the per-directory files holding ignore
+// rules that only apply to syntex, in gitignore syntax.
+const syntexignoreFile = ".syntexignore"
+
 // Manager orchestrates file filtering logic. It handles user-defined exclude
 // patterns and dynamically applies .gitignore rules from multiple repositories
-// with an internal cache to optimize performance.
+// with an internal cache to optimize performance. Rules from .syntexignore
+// files and custom ignore files apply inside and outside of repositories.
+// A Manager is safe for concurrent use.
 type Manager struct {
 	includePatterns  []string
 	excludePatterns  []string
 	disableGitignore bool
 	allowDotfiles    bool
+	// ignoreFilePatterns holds the rules of the custom ignore files, which
+	// apply relative to the working directory.
+	ignoreFilePatterns []gitignore.Pattern
+	// cwd is the working directory exclude patterns are matched relative
+	// to, or empty if it could not be determined.
+	cwd string
 
 	mu          sync.Mutex
 	rootFilters map[string]*gitignoreFilter
+	// roots caches the repository root of each directory looked up, or ""
+	// for directories outside of repositories.
+	roots map[string]string
+	// dirPatterns caches the rules of the .syntexignore file of each directory.
+	dirPatterns map[string][]gitignore.Pattern
+	// dirMatchers caches the matcher of the syntex ignore rules applying to
+	// the entries of each directory, or nil if there are none.
+	dirMatchers map[string]gitignore.Matcher
 }
 
 // Options configures the behavior of the filter Manager.
 type Options struct {
+	// DisableGitignore turns off .gitignore and .syntexignore rules, but not IgnoreFiles.
 	DisableGitignore bool
 	ExcludePatterns  []string
 	IncludePatterns  []string
 	AllowDotfiles    bool
+	// IgnoreFiles lists files of extra gitignore-style rules, whose patterns
+	// are relative to the current working directory.
+	IgnoreFiles []string
 }
 
-// NewManager creates a new filter Manager.
+// NewManager creates a new filter Manager. It fails if one of the custom
+// ignore files cannot be read.
 func NewManager(opts Options) (*Manager, error) {
-	return &Manager{
+	m := &Manager{
 		includePatterns:  opts.IncludePatterns,
 		excludePatterns:  opts.ExcludePatterns,
 		disableGitignore: opts.DisableGitignore,
 		allowDotfiles:    opts.AllowDotfiles,
 		rootFilters:      make(map[string]*gitignoreFilter),
-	}, nil
+		roots:            make(map[string]string),
+		dirPatterns:      make(map[string][]gitignore.Pattern),
+		dirMatchers:      make(map[string]gitignore.Matcher),
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		if len(opts.IgnoreFiles) > 0 {
+			return nil, fmt.Errorf("could not get current working directory: %w", err)
+		}
+		if len(opts.ExcludePatterns) > 0 {
+			fmt.Fprintf(os.Stderr, "warning: could not get current working directory for global exclusion: %v\n", err)
+		}
+	}
+	m.cwd = cwd
+
+	for _, path := range opts.IgnoreFiles {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("could not read ignore file: %w", err)
+		}
+		patterns, err := readPatternFile(path, pathComponents(cwd))
+		if err != nil {
+			return nil, fmt.Errorf("could not read ignore file %q: %w", path, err)
+		}
+		m.ignoreFilePatterns = append(m.ignoreFilePatterns, patterns...)
+	}
+	return m, nil
 }
 
 // GetIncludePatterns returns the configured include patterns.
@@ -78,19 +138,14 @@ func (m *Manager) GetIncludePatterns() []string {
 // It provides robust filtering by attempting to match patterns against both
 // the absolute path and the path relative to the current working directory (CWD).
 func (m *Manager) IsGloballyExcluded(absPath string) bool {
-	cwd, getCwdErr := os.Getwd()
-	if getCwdErr != nil {
-		fmt.Fprintf(os.Stderr, "warning: could not get current working directory for global exclusion: %v\n", getCwdErr)
-	}
-
 	for _, pattern := range m.excludePatterns {
 		// Try matching against the absolute path first.
 		if match, _ := doublestar.Match(pattern, absPath); match {
 			return true
 		}
 
-		if getCwdErr == nil {
-			relPath, err := filepath.Rel(cwd, absPath)
+		if m.cwd != "" {
+			relPath, err := filepath.Rel(m.cwd, absPath)
 			if err == nil {
 				if match, _ := doublestar.Match(pattern, relPath); match {
 					return true
@@ -101,6 +156,12 @@ func (m *Manager) IsGloballyExcluded(absPath string) bool {
 	return false
 }
 
+// IsIgnored checks if a path is ignored by its repository's gitignore rules,
+// by a .syntexignore file or by a custom ignore file. It expects an absolute path.
+func (m *Manager) IsIgnored(absPath string, isDir bool) bool {
+	return m.IsGitIgnored(absPath, isDir) || m.isSyntexIgnored(absPath, isDir)
+}
+
 // IsGitIgnored checks if a path is ignored by a .gitignore file from its
 // containing repository. It expects an absolute path to correctly determine
 // the repository context. It uses a cache to avoid re-parsing .gitignore files.
@@ -109,8 +170,8 @@ func (m *Manager) IsGitIgnored(absPath string, isDir bool) bool {
 		return false
 	}
 
-	root, isRepo, err := project.FindRoot(filepath.Dir(absPath))
-	if err != nil || !isRepo {
+	root, isRepo := m.findRoot(filepath.Dir(absPath))
+	if !isRepo {
 		return false
 	}
 
@@ -127,6 +188,86 @@ func (m *Manager) IsGitIgnored(absPath string, isDir bool) bool {
 	return filter.isIgnored(relPath, isDir)
 }
 
+// isSyntexIgnored checks if a path is ignored by the custom ignore files or
+// by the .syntexignore files of its ancestor directories.
+func (m *Manager) isSyntexIgnored(absPath string, isDir bool) bool {
+	matcher := m.syntexMatcher(filepath.Dir(absPath))
+	return matcher != nil && matcher.Match(pathComponents(absPath), isDir)
+}
+
+// syntexMatcher returns the matcher of the syntex ignore rules applying to
+// the entries of dir, or nil if there are none: the custom ignore files and
+// the .syntexignore files from the project root down to dir, where deeper
+// files take precedence. Matchers are cached by directory. This method is
+// thread-safe.
+func (m *Manager) syntexMatcher(dir string) gitignore.Matcher {
+	m.mu.Lock()
+	matcher, found := m.dirMatchers[dir]
+	m.mu.Unlock()
+	if found {
+		return matcher
+	}
+
+	patterns := slices.Clone(m.ignoreFilePatterns)
+	if !m.disableGitignore {
+		for _, d := range slices.Backward(m.syntexignoreDirs(dir)) {
+			patterns = append(patterns, m.syntexignorePatterns(d)...)
+		}
+	}
+	if len(patterns) > 0 {
+		matcher = gitignore.NewMatcher(patterns)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dirMatchers[dir] = matcher
+	return matcher
+}
+
+// syntexignoreDirs returns dir and its ancestors up to the project root,
+// whose .syntexignore files apply to the entries of dir. The project root
+// is the repository root, or outside of repositories the working
+// directory. Outside of both, only the file in dir applies.
+func (m *Manager) syntexignoreDirs(dir string) []string {
+	top, isRepo := m.findRoot(dir)
+	if !isRepo {
+		top = m.cwd
+	}
+	dirs := []string{dir}
+	rel, err := filepath.Rel(top, dir)
+	if top == "" || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return dirs
+	}
+	for d := dir; d != top; {
+		d = filepath.Dir(d)
+		dirs = append(dirs, d)
+	}
+	return dirs
+}
+
+// syntexignorePatterns returns the rules of the .syntexignore file in dir,
+// reading it on first use. An unreadable file is reported once and has no rules.
+func (m *Manager) syntexignorePatterns(dir string) []gitignore.Pattern {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if patterns, found := m.dirPatterns[dir]; found {
+		return patterns
+	}
+	patterns, err := readPatternFile(filepath.Join(dir, syntexignoreFile), pathComponents(dir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: could not read %s: %v\n", filepath.Join(dir, syntexignoreFile), err)
+	}
+	m.dirPatterns[dir] = patterns
+	return patterns
+}
+
+// pathComponents splits an absolute path into the slash-separated components
+// matched by gitignore patterns whose domain is given by absolute paths.
+func pathComponents(absPath string) []string {
+	return strings.Split(strings.TrimSuffix(filepath.ToSlash(filepath.Clean(absPath)), "/"), "/")
+}
+
 // IsDotfileIgnored determines if a file is hidden and not explicitly matched by its pattern,
 // unless the Manager is configured to include hidden files.
 func (m *Manager) IsDotfileIgnored(filePath, globPattern string) bool {
@@ -157,6 +298,35 @@ func (m *Manager) IsDotfileIgnored(filePath, globPattern string) bool {
 	return false
 }
 
+// findRoot returns the root of the repository containing the directory dir,
+// and false if it is not in a repository or the lookup fails. Results are
+// cached, as opening a repository is the main cost of filtering large trees.
+// The directories between dir and a root found share it, so they are cached
+// as well. This method is thread-safe.
+func (m *Manager) findRoot(dir string) (string, bool) {
+	m.mu.Lock()
+	root, found := m.roots[dir]
+	m.mu.Unlock()
+	if found {
+		return root, root != ""
+	}
+
+	root, isRepo, err := project.FindRoot(dir)
+	if err != nil || !isRepo {
+		root = ""
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.roots[dir] = root
+	if root != "" && strings.HasPrefix(dir, root) {
+		for d := filepath.Dir(dir); len(d) > len(root); d = filepath.Dir(d) {
+			m.roots[d] = root
+		}
+	}
+	return root, root != ""
+}
+
 // getOrCreateFilter retrieves a gitignoreFilter from the cache or creates a new one.
 // This method is thread-safe.
 func (m *Manager) getOrCreateFilter(root string) (*gitignoreFilter, error) {
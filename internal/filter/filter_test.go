@@ -2,16 +2,18 @@
 package filter
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/jbwfu/syntex/internal/project"
 )
 
 // setupTestRepo creates a temporary directory with a git repository,
 // a .gitignore file, and some files/directories to test against.
-func setupTestRepo(t *testing.T) (string, func()) {
+func setupTestRepo(t testing.TB) (string, func()) {
 	t.Helper()
 
 	root, err := os.MkdirTemp("", "syntex-filter-test-")
@@ -238,3 +240,226 @@ func TestFilterManager_IsDotfileIgnored(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterManager_ExcludeFiles(t *testing.T) {
+	repoRoot, cleanup := setupTestRepo(t)
+	defer cleanup()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	globalConfig := filepath.Join(home, "gitconfig-global")
+	systemConfig := filepath.Join(home, "gitconfig-system")
+	t.Setenv("GIT_CONFIG_GLOBAL", globalConfig)
+	t.Setenv("GIT_CONFIG_SYSTEM", systemConfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "")
+
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	// The XDG default is used unless core.excludesFile is set.
+	write(filepath.Join(home, ".config", "git", "ignore"), "*.xdg\n")
+	write(filepath.Join(home, "global-ignore"), "*.swp\nscratch/\nkeep.tmp\n")
+	write(filepath.Join(home, "system-ignore"), "*.bak\n")
+	write(filepath.Join(repoRoot, ".git", "info", "exclude"), "*.tmp\n")
+	write(filepath.Join(repoRoot, ".gitignore"), "*.log\n!important.swp\n")
+
+	testCases := []struct {
+		name        string
+		config      string
+		system      string
+		path        string
+		isDir       bool
+		wantIgnored bool
+	}{
+		{name: "xdg default excludes file", path: "notes.xdg", wantIgnored: true},
+		{name: "excludes file from system config", system: "~/system-ignore", path: "old.bak", wantIgnored: true},
+		{name: "user config overrides system config", config: "~/global-ignore", system: "~/system-ignore", path: "old.bak", wantIgnored: false},
+		{name: "info/exclude", path: "data.tmp", wantIgnored: true},
+		{name: "excludes file from user config", config: "~/global-ignore", path: "main.go.swp", wantIgnored: true},
+		{name: "configured excludes file replaces xdg default", config: "~/global-ignore", path: "notes.xdg", wantIgnored: false},
+		{name: "directory in excludes file", config: "~/global-ignore", path: "scratch", isDir: true, wantIgnored: true},
+		{name: "gitignore overrides excludes file", config: "~/global-ignore", path: "important.swp", wantIgnored: false},
+		{name: "info/exclude overrides excludes file", config: "~/global-ignore", path: "keep.tmp", wantIgnored: true},
+		{name: "repository config overrides user config", config: filepath.Join(home, "missing"), path: "main.go.swp", wantIgnored: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Remove(globalConfig)
+			os.Remove(systemConfig)
+			if tc.system != "" {
+				write(systemConfig, "[core]\n\texcludesFile = "+tc.system+"\n")
+			}
+			if tc.config != "" {
+				write(globalConfig, "[core]\n\texcludesFile = ~/global-ignore\n")
+				if tc.config != "~/global-ignore" {
+					localConfig := filepath.Join(repoRoot, ".git", "config")
+					original, err := os.ReadFile(localConfig)
+					if err != nil {
+						t.Fatalf("failed to read repository config: %v", err)
+					}
+					defer os.WriteFile(localConfig, original, 0644)
+					write(localConfig, string(original)+"\texcludesfile = "+tc.config+"\n")
+				}
+			}
+
+			manager, err := NewManager(Options{})
+			if err != nil {
+				t.Fatalf("NewManager() failed: %v", err)
+			}
+			got := manager.IsGitIgnored(filepath.Join(repoRoot, tc.path), tc.isDir)
+			if got != tc.wantIgnored {
+				t.Errorf("IsGitIgnored(%q) = %v, want %v", tc.path, got, tc.wantIgnored)
+			}
+		})
+	}
+}
+
+func TestFilterManager_Syntexignore(t *testing.T) {
+	// Outside of a git repository, only syntex ignore rules apply.
+	root := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	write(".syntexignore", "fixtures/\n*.pb.go\n")
+	write("api/.syntexignore", "!keep.pb.go\n/local.txt\n")
+	write("custom-ignore", "*.snap\n/api/generated/\n")
+	t.Chdir(root)
+
+	testCases := []struct {
+		name        string
+		path        string
+		isDir       bool
+		ignoreFiles []string
+		noIgnore    bool
+		wantIgnored bool
+	}{
+		{name: "regular file", path: "main.go", wantIgnored: false},
+		{name: "ignored directory", path: "fixtures", isDir: true, wantIgnored: true},
+		{name: "file in ignored directory", path: "fixtures/a/data.json", wantIgnored: true},
+		{name: "pattern from parent directory", path: "api/v1/service.pb.go", wantIgnored: true},
+		{name: "negation in nested file", path: "api/keep.pb.go", wantIgnored: false},
+		{name: "anchored to nested directory", path: "api/local.txt", wantIgnored: true},
+		{name: "anchored pattern does not match elsewhere", path: "local.txt", wantIgnored: false},
+		{name: "custom ignore file", path: "ui/button.snap", ignoreFiles: []string{"custom-ignore"}, wantIgnored: true},
+		{name: "anchored pattern in custom ignore file", path: "api/generated/x.go", ignoreFiles: []string{"custom-ignore"}, wantIgnored: true},
+		{name: "disabled ignore rules", path: "fixtures/data.json", noIgnore: true, wantIgnored: false},
+		{name: "custom ignore file with disabled ignore rules", path: "a.snap", ignoreFiles: []string{"custom-ignore"}, noIgnore: true, wantIgnored: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager, err := NewManager(Options{IgnoreFiles: tc.ignoreFiles, DisableGitignore: tc.noIgnore})
+			if err != nil {
+				t.Fatalf("NewManager() failed: %v", err)
+			}
+			got := manager.IsIgnored(filepath.Join(root, filepath.FromSlash(tc.path)), tc.isDir)
+			if got != tc.wantIgnored {
+				t.Errorf("IsIgnored(%q) = %v, want %v", tc.path, got, tc.wantIgnored)
+			}
+		})
+	}
+
+	if _, err := NewManager(Options{IgnoreFiles: []string{"missing-ignore"}}); err == nil {
+		t.Error("NewManager() with a missing ignore file should fail")
+	}
+}
+
+func TestFilterManager_SyntexignoreProjectRoot(t *testing.T) {
+	// Outside of a git repository, .syntexignore files are read up to the
+	// working directory, and a file outside of it only follows its own directory's.
+	parent := t.TempDir()
+	project := filepath.Join(parent, "project")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(parent, ".syntexignore"), []byte("*.txt\n"), 0644); err != nil {
+		t.Fatalf("failed to write .syntexignore: %v", err)
+	}
+	t.Chdir(project)
+
+	manager, err := NewManager(Options{})
+	if err != nil {
+		t.Fatalf("NewManager() failed: %v", err)
+	}
+	if manager.IsIgnored(filepath.Join(project, "notes.txt"), false) {
+		t.Error("IsIgnored() applied a .syntexignore file above the working directory")
+	}
+	if !manager.IsIgnored(filepath.Join(parent, "notes.txt"), false) {
+		t.Error("IsIgnored() did not apply the .syntexignore file of a directory outside the working directory")
+	}
+}
+
+// benchmarkTree adds dirs directories of files source files each to the test
+// repository and returns the absolute paths of the files.
+func benchmarkTree(b *testing.B, root string, dirs, files int) []string {
+	b.Helper()
+	var paths []string
+	for i := range dirs {
+		dir := filepath.Join(root, "pkg", fmt.Sprintf("dir%03d", i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			b.Fatalf("failed to create %s: %v", dir, err)
+		}
+		for j := range files {
+			path := filepath.Join(dir, fmt.Sprintf("file%03d.go", j))
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				b.Fatalf("failed to write %s: %v", path, err)
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// BenchmarkFindRootPerFile measures looking up the repository of every file
+// without a cache, the cost Manager avoids.
+func BenchmarkFindRootPerFile(b *testing.B) {
+	repoRoot, cleanup := setupTestRepo(b)
+	defer cleanup()
+	paths := benchmarkTree(b, repoRoot, 20, 50)
+
+	b.ResetTimer()
+	for b.Loop() {
+		for _, path := range paths {
+			if _, _, err := project.FindRoot(filepath.Dir(path)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkManager_IsIgnored measures filtering every file of a tree with a
+// new Manager, as done when planning a pack.
+func BenchmarkManager_IsIgnored(b *testing.B) {
+	repoRoot, cleanup := setupTestRepo(b)
+	defer cleanup()
+	paths := benchmarkTree(b, repoRoot, 20, 50)
+
+	b.ResetTimer()
+	for b.Loop() {
+		manager, err := NewManager(Options{ExcludePatterns: []string{"**/*.exe"}})
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, path := range paths {
+			if manager.IsGloballyExcluded(path) || manager.IsIgnored(path, false) {
+				b.Fatalf("%s should not be filtered", path)
+			}
+		}
+	}
+}
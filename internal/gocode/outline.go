@@ -0,0 +1,54 @@
+package gocode
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+)
+
+// Outline reduces Go source to its shape: the package clause, imports,
+// constants, variables, type declarations and function signatures, each
+// with their doc comments. Function and method bodies are removed together
+// with the comments inside them. The result is gofmt-formatted.
+func Outline(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("parsing Go source: %w", err)
+	}
+
+	var bodies []*ast.BlockStmt
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			bodies = append(bodies, fn.Body)
+			fn.Body = nil
+		}
+	}
+
+	comments := file.Comments[:0]
+	for _, group := range file.Comments {
+		if !insideAny(group, bodies) {
+			comments = append(comments, group)
+		}
+	}
+	file.Comments = comments
+
+	var out bytes.Buffer
+	if err := format.Node(&out, fset, file); err != nil {
+		return nil, fmt.Errorf("printing outline: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// insideAny reports whether a node lies within any of the given blocks.
+func insideAny(node ast.Node, blocks []*ast.BlockStmt) bool {
+	for _, block := range blocks {
+		if node.Pos() >= block.Lbrace && node.End() <= block.Rbrace+1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package gocode
+
+import "testing"
+
+func TestOutline(t *testing.T) {
+	src := `// Package demo is a demo.
+package demo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Version is the demo version.
+const Version = "1.0"
+
+// Greeter greets people.
+type Greeter struct {
+	// Name is who to greet.
+	Name string
+}
+
+// Greet returns a greeting.
+func (g *Greeter) Greet() string {
+	// Build the greeting.
+	return fmt.Sprintf("hello, %s", strings.TrimSpace(g.Name))
+}
+
+func helper(a, b int) (int, error) {
+	/* block comment inside the body */
+	return a + b, nil
+}
+`
+
+	want := `// Package demo is a demo.
+package demo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Version is the demo version.
+const Version = "1.0"
+
+// Greeter greets people.
+type Greeter struct {
+	// Name is who to greet.
+	Name string
+}
+
+// Greet returns a greeting.
+func (g *Greeter) Greet() string
+
+func helper(a, b int) (int, error)
+`
+
+	got, err := Outline([]byte(src))
+	if err != nil {
+		t.Fatalf("Outline() returned an unexpected error: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Outline() mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestOutline_InvalidSource(t *testing.T) {
+	if _, err := Outline([]byte("package x\nfunc {")); err == nil {
+		t.Error("Outline() expected an error for invalid Go source")
+	}
+}
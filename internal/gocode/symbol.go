@@ -0,0 +1,121 @@
+// Package gocode extracts structural information from Go source files.
+package gocode
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// FindSymbol locates the declaration of a top-level symbol in Go source and
+// returns the 1-based, inclusive line span it occupies, including its doc
+// comment. The name is either a plain identifier such as "run" or "Options",
+// or a method qualified by its receiver type such as "Packer.Plan".
+func FindSymbol(src []byte, name string) (startLine, endLine int, err error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing Go source: %w", err)
+	}
+
+	receiver, ident, isMethod := strings.Cut(name, ".")
+	if !isMethod {
+		ident, receiver = receiver, ""
+	}
+
+	for _, decl := range file.Decls {
+		if start, end, ok := matchDecl(decl, receiver, ident); ok {
+			return fset.Position(start).Line, fset.Position(end).Line, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("symbol %q not found", name)
+}
+
+// matchDecl reports whether decl declares the identifier, and returns the
+// position span of the declaration including its doc comment.
+func matchDecl(decl ast.Decl, receiver, ident string) (start, end token.Pos, ok bool) {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Name.Name != ident || receiverName(d) != receiver {
+			return 0, 0, false
+		}
+		return withDoc(d.Doc, d.Pos()), d.End(), true
+
+	case *ast.GenDecl:
+		if receiver != "" {
+			return 0, 0, false
+		}
+		for _, spec := range d.Specs {
+			if !specDeclares(spec, ident) {
+				continue
+			}
+			// An ungrouped declaration is returned whole, including its keyword.
+			if !d.Lparen.IsValid() {
+				return withDoc(d.Doc, d.Pos()), d.End(), true
+			}
+			return withDoc(specDoc(spec), spec.Pos()), spec.End(), true
+		}
+	}
+	return 0, 0, false
+}
+
+// receiverName returns the base type name of a method receiver, or an empty
+// string for plain functions.
+func receiverName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	expr := fn.Recv.List[0].Type
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.ParenExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// specDeclares reports whether a type, const or var spec declares ident.
+func specDeclares(spec ast.Spec, ident string) bool {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Name.Name == ident
+	case *ast.ValueSpec:
+		for _, n := range s.Names {
+			if n.Name == ident {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// specDoc returns the doc comment attached to a spec inside a grouped declaration.
+func specDoc(spec ast.Spec) *ast.CommentGroup {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Doc
+	case *ast.ValueSpec:
+		return s.Doc
+	}
+	return nil
+}
+
+// withDoc returns the start of the doc comment if there is one, or pos otherwise.
+func withDoc(doc *ast.CommentGroup, pos token.Pos) token.Pos {
+	if doc != nil {
+		return doc.Pos()
+	}
+	return pos
+}
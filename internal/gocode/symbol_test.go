@@ -0,0 +1,67 @@
+package gocode
+
+import "testing"
+
+const symbolSource = `package demo
+
+import "fmt"
+
+// Greeting is the default greeting.
+const Greeting = "hello"
+
+// Limits groups the size limits.
+const (
+	// MaxSize is the largest size.
+	MaxSize = 10
+	MinSize = 1
+)
+
+// Box holds a value.
+type Box[T any] struct {
+	v T
+}
+
+// Get returns the value.
+func (b *Box[T]) Get() T {
+	return b.v
+}
+
+// run prints the greeting.
+func run() {
+	fmt.Println(Greeting)
+}
+
+func (b Box[T]) run() {}
+`
+
+func TestFindSymbol(t *testing.T) {
+	testCases := []struct {
+		name      string
+		symbol    string
+		wantStart int
+		wantEnd   int
+		wantErr   bool
+	}{
+		{name: "function with doc comment", symbol: "run", wantStart: 25, wantEnd: 28},
+		{name: "method on generic pointer receiver", symbol: "Box.Get", wantStart: 20, wantEnd: 23},
+		{name: "method with the same name as a function", symbol: "Box.run", wantStart: 30, wantEnd: 30},
+		{name: "type declaration", symbol: "Box", wantStart: 15, wantEnd: 18},
+		{name: "ungrouped const", symbol: "Greeting", wantStart: 5, wantEnd: 6},
+		{name: "const inside a group", symbol: "MaxSize", wantStart: 10, wantEnd: 11},
+		{name: "undocumented const inside a group", symbol: "MinSize", wantStart: 12, wantEnd: 12},
+		{name: "unknown symbol", symbol: "missing", wantErr: true},
+		{name: "function is not a method", symbol: "Box.missing", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start, end, err := FindSymbol([]byte(symbolSource), tc.symbol)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("FindSymbol(%q) error = %v, wantErr %v", tc.symbol, err, tc.wantErr)
+			}
+			if start != tc.wantStart || end != tc.wantEnd {
+				t.Errorf("FindSymbol(%q) = %d-%d, want %d-%d", tc.symbol, start, end, tc.wantStart, tc.wantEnd)
+			}
+		})
+	}
+}
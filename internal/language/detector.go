@@ -2,10 +2,10 @@ package language
 
 import (
 	"io"
-	"os"
 	"strings"
 
 	"github.com/go-enry/go-enry/v2"
+	"github.com/jbwfu/syntex/internal/source"
 )
 
 const readBufferSize = 8192
@@ -17,11 +17,20 @@ type DetectionResult struct {
 }
 
 // Detector determines the language identifier for a given filename.
-type Detector struct{}
+// It is safe for concurrent use.
+type Detector struct {
+	source source.Source
+}
 
-// NewDetector creates and initializes a new Detector.
+// NewDetector creates and initializes a new Detector reading files from the
+// local filesystem.
 func NewDetector() *Detector {
-	return &Detector{}
+	return NewSourceDetector(source.NewOS())
+}
+
+// NewSourceDetector creates a Detector reading files from src.
+func NewSourceDetector(src source.Source) *Detector {
+	return &Detector{source: src}
 }
 
 // AnalyzeFile determines the language and binary status of a file by its path.
@@ -35,7 +44,7 @@ func (d *Detector) AnalyzeFile(path string) (*DetectionResult, error) {
 	}
 
 	if !ok {
-		file, err := os.Open(path)
+		file, err := d.source.Open(path)
 		if err != nil {
 			return nil, err
 		}
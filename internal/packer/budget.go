@@ -0,0 +1,239 @@
+package packer
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// fileOverheadTokens approximates the tokens spent on the heading and
+// delimiters around each file, in addition to its path, when there is no
+// formatter to measure them with.
+const fileOverheadTokens = 8
+
+// minTruncatedTokens is the smallest remaining budget worth spending on a
+// truncated file rather than omitting it.
+const minTruncatedTokens = 64
+
+// applyBudget marks files as omitted or truncated so the pack, including
+// the preamble and trailer, fits into MaxTokens. Files are considered in
+// priority order: matches of earlier PriorityGlobs first, then explicit
+// targets before glob matches, then either the smallest files or the plan order.
+func (p *Packer) applyBudget(plan []PlannedFile) {
+	p.CountTokens(plan)
+	tokens := make([]int, len(plan))
+	for i, file := range plan {
+		tokens[i] = file.Tokens
+	}
+
+	// The preamble and trailer are estimated file by file, which misjudges
+	// what files share, such as the directories of the tree. The budget
+	// shrinks by whatever the whole document still exceeds it.
+	budget := p.opts.MaxTokens
+	for {
+		for i := range plan {
+			plan[i].Tokens, plan[i].Piece = tokens[i], nil
+			plan[i].Truncated, plan[i].Omitted = false, false
+		}
+		packed, err := p.allocateBudget(plan, budget)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not measure the document preamble and trailer: %v\n", err)
+			return
+		}
+
+		included, omitted := p.partitionPlan(plan)
+		frame, err := p.measureDocumentFrame(p.newDocument(included), omitted)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not measure the document preamble and trailer: %v\n", err)
+			return
+		}
+		excess := packed + frame.Tokens - p.opts.MaxTokens
+		if excess <= 0 || len(included) == 0 {
+			return
+		}
+		budget -= excess
+	}
+}
+
+// allocateBudget marks files as omitted or truncated so that the document
+// fits into budget tokens. A packed file costs its content, its overhead
+// and its entries in the preamble, and an omitted file its entry in the
+// trailer. It returns the tokens of the packed files, without the preamble
+// and trailer.
+func (p *Packer) allocateBudget(plan []PlannedFile, budget int) (int, error) {
+	var deleted []PlannedFile
+	for _, file := range plan {
+		if file.Deleted && !p.packsDeleted() {
+			deleted = append(deleted, file)
+		}
+	}
+	base, err := p.measureDocumentFrame(p.newDocument(nil), deleted)
+	if err != nil {
+		return 0, err
+	}
+
+	// Every file starts out omitted, and gives its trailer entry back
+	// when it is packed.
+	listed := make([]int, len(plan))
+	remaining := budget - base.Tokens
+	for i, file := range plan {
+		if file.Deleted && !p.packsDeleted() {
+			continue
+		}
+		omitted := file
+		omitted.Omitted = true
+		if listed[i], err = p.frameEntry(base, nil, []PlannedFile{omitted}, deleted); err != nil {
+			return 0, err
+		}
+		remaining -= listed[i]
+	}
+
+	packed := 0
+	for _, i := range p.priorityOrder(plan) {
+		file := &plan[i]
+		if file.Deleted && !p.packsDeleted() {
+			continue
+		}
+		remaining += listed[i]
+
+		entry, err := p.frameEntry(base, []PlannedFile{*file}, nil, deleted)
+		if err != nil {
+			return 0, err
+		}
+		if cost := file.Tokens + p.fileOverhead(*file); cost+entry <= remaining {
+			remaining -= cost + entry
+			packed += cost
+			continue
+		}
+
+		// Diffs cover whole files and cannot be truncated by line range.
+		if p.opts.Truncate && p.opts.Diff == nil {
+			// A truncated file is also listed as such in the trailer.
+			truncated := *file
+			truncated.Truncated, truncated.Piece = true, &LineRange{Start: 1, End: math.MaxInt32}
+			if entry, err = p.frameEntry(base, []PlannedFile{truncated}, nil, deleted); err != nil {
+				return 0, err
+			}
+			if remaining-entry >= minTruncatedTokens {
+				if used, ok := p.truncate(file, remaining-entry); ok {
+					remaining -= used + entry
+					packed += used
+					continue
+				}
+			}
+		}
+		file.Omitted = true
+		remaining -= listed[i]
+	}
+	return packed, nil
+}
+
+// frameEntry returns the tokens the preamble and trailer spend on the given
+// included or omitted files, beyond base, the size of a document without
+// them. The deleted files are listed in the trailer in either case.
+func (p *Packer) frameEntry(base SplitLimit, included, omitted, deleted []PlannedFile) (int, error) {
+	frame, err := p.measureDocumentFrame(p.newDocument(included), append(omitted, deleted...))
+	if err != nil {
+		return 0, err
+	}
+	return frame.Tokens - base.Tokens, nil
+}
+
+// fileOverhead returns the tokens spent on a file besides its content: its
+// display name and the delimiters the formatter puts around it.
+func (p *Packer) fileOverhead(file PlannedFile) int {
+	if p.formatter != nil {
+		if size, err := p.measureFile(file, nil); err == nil {
+			return size.Tokens
+		}
+	}
+	return p.tokenizer().Count([]byte(file.DisplayName())) + fileOverheadTokens
+}
+
+// priorityOrder returns the indices of plan sorted by budget priority.
+func (p *Packer) priorityOrder(plan []PlannedFile) []int {
+	tiers := make([]int, len(plan))
+	for i, file := range plan {
+		tiers[i] = p.priorityTier(file.Path)
+	}
+
+	order := make([]int, len(plan))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		fa, fb := plan[order[a]], plan[order[b]]
+		if ta, tb := tiers[order[a]], tiers[order[b]]; ta != tb {
+			return ta < tb
+		}
+		if fa.Explicit != fb.Explicit {
+			return fa.Explicit
+		}
+		if p.opts.SmallestFirst {
+			return fa.Tokens < fb.Tokens
+		}
+		return false
+	})
+	return order
+}
+
+// priorityTier returns the index of the first priority glob matching path,
+// or len(PriorityGlobs) if none does.
+func (p *Packer) priorityTier(path string) int {
+	slashed := filepath.ToSlash(path)
+	for i, pattern := range p.opts.PriorityGlobs {
+		if match, _ := doublestar.Match(filepath.ToSlash(pattern), slashed); match {
+			return i
+		}
+	}
+	return len(p.opts.PriorityGlobs)
+}
+
+// truncate restricts file to as many leading lines of its transformed
+// content as fit into budget tokens, together with its overhead, which
+// grows by the line range in its name. It returns the tokens used,
+// including the overhead, and false if not even the first line fits.
+func (p *Packer) truncate(file *PlannedFile, budget int) (int, bool) {
+	content, err := p.readTransformed(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: could not truncate %s: %v\n", file.Path, err)
+		return 0, false
+	}
+
+	first := file.firstLine()
+	counter := p.tokenizer()
+
+	piece := *file
+	used, lines := 0, 0
+	for len(content) > 0 {
+		line := content
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			line = content[:i+1]
+		}
+		cost := counter.Count(line)
+		if p.opts.LineNumbers {
+			cost += counter.Count(fmt.Appendf(nil, "%d | ", first+lines))
+		}
+		piece.Piece = &LineRange{Start: 1, End: lines + 1}
+		if used+cost+p.fileOverhead(piece) > budget {
+			break
+		}
+		used += cost
+		lines++
+		content = content[len(line):]
+	}
+
+	if lines == 0 {
+		return 0, false
+	}
+	file.Piece = &LineRange{Start: 1, End: lines}
+	file.Truncated = true
+	file.Tokens = used
+	return used + p.fileOverhead(*file), true
+}
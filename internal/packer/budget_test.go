@@ -0,0 +1,215 @@
+package packer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// byteCounter is a deterministic token.Counter that counts one token per byte.
+type byteCounter struct{}
+
+func (byteCounter) Count(text []byte) int { return len(text) }
+
+func TestPacker_ApplyBudget(t *testing.T) {
+	dir := t.TempDir()
+	sizes := map[string]int{"a.go": 100, "b.go": 40, "c.go": 60, "d.md": 30}
+
+	// Every file name has the same length, so the per-file overhead is constant.
+	var plan []PlannedFile
+	for _, name := range []string{"a.go", "b.go", "c.go", "d.md"} {
+		path := filepath.Join(dir, name)
+		content := strings.Repeat(strings.Repeat("x", 9)+"\n", sizes[name]/10)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		plan = append(plan, PlannedFile{Path: path, Language: "text"})
+	}
+	overhead := len(plan[0].Path) + fileOverheadTokens
+
+	testCases := []struct {
+		name          string
+		maxTokens     int
+		explicit      []string
+		priorityGlobs []string
+		smallestFirst bool
+		truncate      bool
+		wantOmitted   []string
+		wantTruncated map[string]LineRange
+	}{
+		{
+			name:        "everything fits",
+			maxTokens:   230 + 4*overhead,
+			wantOmitted: nil,
+		},
+		{
+			name:        "path order drops files once the budget is spent",
+			maxTokens:   140 + 2*overhead,
+			wantOmitted: []string{"c.go", "d.md"},
+		},
+		{
+			name:        "smaller later files still fill the remaining budget",
+			maxTokens:   170 + 3*overhead,
+			wantOmitted: []string{"c.go"},
+		},
+		{
+			name:          "smallest first",
+			maxTokens:     130 + 3*overhead,
+			smallestFirst: true,
+			wantOmitted:   []string{"a.go"},
+		},
+		{
+			name:        "explicit targets before glob matches",
+			maxTokens:   60 + overhead,
+			explicit:    []string{"c.go"},
+			wantOmitted: []string{"a.go", "b.go", "d.md"},
+		},
+		{
+			name:          "priority globs before explicit targets",
+			maxTokens:     30 + overhead,
+			explicit:      []string{"c.go"},
+			priorityGlobs: []string{"**/*.md"},
+			wantOmitted:   []string{"a.go", "b.go", "c.go"},
+		},
+		{
+			name:          "truncate the first file that does not fit",
+			maxTokens:     75 + overhead,
+			truncate:      true,
+			wantOmitted:   []string{"b.go", "c.go", "d.md"},
+			wantTruncated: map[string]LineRange{"a.go": {Start: 1, End: 7}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			candidatePlan := make([]PlannedFile, len(plan))
+			copy(candidatePlan, plan)
+			for i := range candidatePlan {
+				for _, name := range tc.explicit {
+					if filepath.Base(candidatePlan[i].Path) == name {
+						candidatePlan[i].Explicit = true
+					}
+				}
+			}
+
+			p := NewPacker(nil, nil, nil, nil, Options{
+				Tokenizer:     byteCounter{},
+				MaxTokens:     tc.maxTokens,
+				PriorityGlobs: tc.priorityGlobs,
+				SmallestFirst: tc.smallestFirst,
+				Truncate:      tc.truncate,
+			})
+			p.applyBudget(candidatePlan)
+
+			var gotOmitted []string
+			for _, file := range candidatePlan {
+				name := filepath.Base(file.Path)
+				if file.Omitted {
+					gotOmitted = append(gotOmitted, name)
+				}
+
+				wantRange, wantTruncated := tc.wantTruncated[name]
+				if file.Truncated != wantTruncated {
+					t.Errorf("%s: Truncated = %v, want %v", name, file.Truncated, wantTruncated)
+				}
+				if wantTruncated && (file.Piece == nil || *file.Piece != wantRange) {
+					t.Errorf("%s: Piece = %v, want %v", name, file.Piece, wantRange)
+				}
+			}
+
+			if strings.Join(gotOmitted, ",") != strings.Join(tc.wantOmitted, ",") {
+				t.Errorf("omitted = %v, want %v", gotOmitted, tc.wantOmitted)
+			}
+		})
+	}
+}
+
+func TestPacker_BudgetCoversDocumentFrame(t *testing.T) {
+	t.Chdir(t.TempDir())
+	var plan []PlannedFile
+	for i := range 12 {
+		path := fmt.Sprintf("file%02d.txt", i)
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", 99)+"\n"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		plan = append(plan, PlannedFile{Path: path, Language: "text"})
+	}
+
+	const maxTokens = 1200
+	for _, name := range []string{"markdown", "org", "xml", "json"} {
+		t.Run(name, func(t *testing.T) {
+			formatter, err := NewFormatter(name)
+			if err != nil {
+				t.Fatalf("NewFormatter(%q) returned an unexpected error: %v", name, err)
+			}
+			var out bytes.Buffer
+			p := NewPacker(formatter, &out, nil, nil, Options{
+				Header:    &Header{Title: "demo", GeneratedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+				Tree:      true,
+				Tokenizer: byteCounter{},
+				MaxTokens: maxTokens,
+				Truncate:  true,
+			})
+
+			candidatePlan := slices.Clone(plan)
+			p.applyBudget(candidatePlan)
+			if err := p.Execute(candidatePlan); err != nil {
+				t.Fatalf("Execute() returned an unexpected error: %v", err)
+			}
+
+			if out.Len() > maxTokens {
+				t.Errorf("output has %d tokens, want at most %d:\n%s", out.Len(), maxTokens, out.String())
+			}
+			included, omitted := p.partitionPlan(candidatePlan)
+			if len(included) == 0 || len(omitted) == 0 {
+				t.Errorf("packed %d files and omitted %d, want some of each:\n%s", len(included), len(omitted), out.String())
+			}
+		})
+	}
+}
+
+func TestPacker_TruncateOutline(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("package demo\n")
+	for i := range 20 {
+		fmt.Fprintf(&src, "\nfunc F%02d() int {\n\tx := %d\n\treturn x * x\n}\n", i, i)
+	}
+	t.Chdir(t.TempDir())
+	path := "demo.go"
+	if err := os.WriteFile(path, []byte(src.String()), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+
+	const maxTokens = 200
+	var out bytes.Buffer
+	p := NewPacker(NewMarkdownFormatter(), &out, nil, nil, Options{
+		Transforms: []Transform{NewOutlineTransform()},
+		Tokenizer:  byteCounter{},
+		MaxTokens:  maxTokens,
+		Truncate:   true,
+	})
+	plan := []PlannedFile{{Path: path, Language: "go"}}
+	p.applyBudget(plan)
+	if !plan[0].Truncated {
+		t.Fatalf("file was not truncated: %+v", plan[0])
+	}
+	if err := p.Execute(plan); err != nil {
+		t.Fatalf("Execute() returned an unexpected error: %v", err)
+	}
+
+	got := out.String()
+	if out.Len() > maxTokens {
+		t.Errorf("output has %d tokens, want at most %d:\n%s", out.Len(), maxTokens, got)
+	}
+	if strings.Contains(got, "return") {
+		t.Errorf("truncated outline contains function bodies:\n%s", got)
+	}
+	if !strings.Contains(got, "func F00() int") {
+		t.Errorf("truncated outline does not start with the first function:\n%s", got)
+	}
+}
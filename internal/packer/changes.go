@@ -0,0 +1,93 @@
+package packer
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/jbwfu/syntex/internal/project"
+	"github.com/jbwfu/syntex/internal/source"
+)
+
+// changeSet indexes the git changes a plan is restricted to.
+type changeSet struct {
+	// present maps the absolute paths of added, modified and renamed files to their change.
+	present map[string]project.Change
+	deleted []project.Change
+}
+
+// newChangeSet indexes changes, or returns nil if the plan is not restricted to git changes.
+func newChangeSet(changes []project.Change) *changeSet {
+	if changes == nil {
+		return nil
+	}
+	cs := &changeSet{present: make(map[string]project.Change)}
+	for _, change := range changes {
+		if change.Status == project.Deleted {
+			cs.deleted = append(cs.deleted, change)
+		} else {
+			cs.present[change.Path] = change
+		}
+	}
+	return cs
+}
+
+// paths returns the paths of all changed files that still exist, for
+// planning when no targets were given.
+func (cs *changeSet) paths() []string {
+	paths := make([]string, 0, len(cs.present))
+	for path := range cs.present {
+		paths = append(paths, displayPath(path))
+	}
+	return paths
+}
+
+// deletedFiles returns the deleted files that match any of patterns, or all
+// of them if matchAll is set, as planned files without content.
+func (p *Packer) deletedFiles(cs *changeSet, patterns []string, matchAll bool) []PlannedFile {
+	var files []PlannedFile
+	for _, change := range cs.deleted {
+		path := displayPath(change.Path)
+		pattern, ok := matchingPattern(p.source(), patterns, path, change.Path)
+		if !ok && !matchAll {
+			continue
+		}
+		if p.filter.IsDotfileIgnored(path, pattern) || p.filter.IsGloballyExcluded(change.Path) {
+			continue
+		}
+		files = append(files, PlannedFile{Path: path, Deleted: true})
+	}
+	return files
+}
+
+// matchingPattern returns the first pattern matching a file, given by its
+// display and absolute path. The file need not exist.
+func matchingPattern(src source.Source, patterns []string, path, absPath string) (string, bool) {
+	for _, pattern := range patterns {
+		prepared, err := preparePattern(src, pattern)
+		if err != nil {
+			continue
+		}
+		candidate := path
+		if filepath.IsAbs(prepared) {
+			candidate = absPath
+		}
+		if ok, _ := doublestar.PathMatch(filepath.Clean(prepared), candidate); ok {
+			return prepared, true
+		}
+	}
+	return "", false
+}
+
+// displayPath returns path relative to the working directory if possible.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,246 @@
+package packer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/jbwfu/syntex/internal/filter"
+	"github.com/jbwfu/syntex/internal/language"
+	"github.com/jbwfu/syntex/internal/project"
+	"github.com/jbwfu/syntex/internal/source"
+)
+
+func TestPacker_PlanChanges(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	abs := func(path string) string { return filepath.Join(wd, path) }
+
+	// Changed paths may contain glob metacharacters.
+	page := filepath.Join("app", "[id]", "page.tsx")
+	if err := os.MkdirAll(filepath.Dir(page), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(page, []byte("export default function Page() {}\n"), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", page, err)
+	}
+
+	changes := []project.Change{
+		{Path: abs(page), Status: project.Added},
+		{Path: abs("main.go"), Status: project.Modified},
+		{Path: abs("main.log"), Status: project.Added},
+		{Path: abs("src/app.go"), OldPath: abs("src/old.go"), Status: project.Renamed},
+		{Path: abs("src/gone.go"), Status: project.Deleted},
+		{Path: abs("vendor/lib/lib.go"), Status: project.Modified},
+	}
+
+	// plannedChange holds the fields of a PlannedFile relevant to changes.
+	type plannedChange struct {
+		Path        string
+		Deleted     bool
+		RenamedFrom string
+	}
+
+	testCases := []struct {
+		name    string
+		targets []string
+		want    []plannedChange
+	}{
+		{
+			name: "all changes without targets, respecting ignore rules",
+			want: []plannedChange{
+				{Path: page},
+				{Path: "main.go"},
+				{Path: filepath.Join("src", "app.go"), RenamedFrom: filepath.Join("src", "old.go")},
+				{Path: filepath.Join("src", "gone.go"), Deleted: true},
+			},
+		},
+		{
+			name:    "changes within targets",
+			targets: []string{"src"},
+			want: []plannedChange{
+				{Path: filepath.Join("src", "app.go"), RenamedFrom: filepath.Join("src", "old.go")},
+				{Path: filepath.Join("src", "gone.go"), Deleted: true},
+			},
+		},
+		{
+			name:    "unchanged targets",
+			targets: []string{"README.md"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filterManager, err := filter.NewManager(filter.Options{})
+			if err != nil {
+				t.Fatalf("failed to create filter manager: %v", err)
+			}
+			p := NewPacker(nil, nil, filterManager, language.NewDetector(), Options{Changes: changes})
+
+			plan, err := p.Plan(tc.targets)
+			if err != nil {
+				t.Fatalf("Plan() returned an unexpected error: %v", err)
+			}
+
+			var got []plannedChange
+			for _, file := range plan {
+				got = append(got, plannedChange{Path: file.Path, Deleted: file.Deleted, RenamedFrom: file.RenamedFrom})
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Plan() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPacker_ExecuteDiff(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	repo, err := git.PlainOpen(wd)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	for _, name := range []string{"main.go", "gone.go"} {
+		if err := os.WriteFile(name, []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatalf("failed to stage file: %v", err)
+		}
+	}
+	_, err = wt.Commit("base", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if err := os.WriteFile("main.go", []byte("package app\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Remove("gone.go"); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	differ, err := project.NewDiffer(wd, "HEAD")
+	if err != nil {
+		t.Fatalf("NewDiffer() returned an unexpected error: %v", err)
+	}
+	plan := []PlannedFile{
+		{Path: "main.go", Language: "go"},
+		{Path: "README.md", Language: "markdown"},
+		{Path: "gone.go", Deleted: true},
+	}
+
+	testCases := []struct {
+		name        string
+		withContent bool
+		want        string
+	}{
+		{
+			name: "diff only",
+			want: "- main.go (diff)\n```diff\ndiff --git a/main.go b/main.go\n",
+		},
+		{
+			name:        "content before diff",
+			withContent: true,
+			want: "- main.go\n```go\npackage app\n\n```\n\n" +
+				"- main.go (diff)\n```diff\ndiff --git a/main.go b/main.go\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			opts := Options{Diff: differ, DiffWithContent: tc.withContent}
+			p := NewPacker(NewMarkdownFormatter(), &out, nil, nil, opts)
+			if err := p.Execute(plan); err != nil {
+				t.Fatalf("Execute() returned an unexpected error: %v", err)
+			}
+
+			got := out.String()
+			if !strings.HasPrefix(got, tc.want) {
+				t.Errorf("Execute() output does not start with the expected blocks:\ngot:\n%s\nwant prefix:\n%s", got, tc.want)
+			}
+			if !strings.Contains(got, "-package main\n+package app\n") {
+				t.Errorf("Execute() output is missing the hunk:\n%s", got)
+			}
+			// README.md exists but is untracked, so it is diffed as a new file.
+			if !strings.Contains(got, "- README.md (diff)\n") {
+				t.Errorf("Execute() output is missing the diff of README.md:\n%s", got)
+			}
+			// Deleted files are packed as their removal diff and still listed as deleted.
+			if !strings.Contains(got, "- gone.go (diff)\n```diff\ndiff --git a/gone.go b/gone.go\n") ||
+				!strings.Contains(got, "+++ /dev/null\n@@ -1 +0,0 @@\n-package main\n") {
+				t.Errorf("Execute() output is missing the removal diff of gone.go:\n%s", got)
+			}
+			if !strings.Contains(got, "Deleted:\n- gone.go\n") {
+				t.Errorf("Execute() output does not list gone.go as deleted:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestMatchingPattern(t *testing.T) {
+	dir := t.TempDir()
+	absPath := filepath.Join(dir, "src", "a.go")
+	path := filepath.Join("src", "a.go")
+
+	testCases := []struct {
+		name     string
+		patterns []string
+		want     string
+		wantOK   bool
+	}{
+		{
+			name:     "relative pattern",
+			patterns: []string{"src/*.go"},
+			want:     "src/*.go",
+			wantOK:   true,
+		},
+		{
+			name:     "absolute pattern",
+			patterns: []string{filepath.Join(dir, "src", "*.go")},
+			want:     filepath.Join(dir, "src", "*.go"),
+			wantOK:   true,
+		},
+		{
+			name:     "relative pattern after a non-matching absolute one",
+			patterns: []string{filepath.Join(dir, "docs", "*.md"), "src/*.go"},
+			want:     "src/*.go",
+			wantOK:   true,
+		},
+		{
+			name:     "no match",
+			patterns: []string{"docs/*.md"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := matchingPattern(source.NewOS(), tc.patterns, path, absPath)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("matchingPattern() = %q, %v, want %q, %v", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
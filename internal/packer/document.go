@@ -0,0 +1,112 @@
+package packer
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// Header holds the metadata rendered in a document preamble.
+type Header struct {
+	Title       string
+	GeneratedAt time.Time
+	// Commit is the abbreviated git commit of the project, or empty outside a repository.
+	Commit string
+}
+
+// Document describes the pack as a whole. It is passed to DocumentFormatter.Begin.
+type Document struct {
+	// Header is nil unless a preamble was requested.
+	Header *Header
+	// Files lists every planned file in output order.
+	Files []PlannedFile
+	// Tree is the directory hierarchy of Files, or nil unless a tree was requested.
+	Tree *TreeNode
+	// Part and Parts number the document when the output is split into
+	// several parts. Both are zero for unsplit output.
+	Part, Parts int
+}
+
+// Summary describes what was written. It is passed to DocumentFormatter.End.
+type Summary struct {
+	Header *Header
+	Files  int
+	Lines  int
+	Bytes  int64
+	// Truncated lists the packed files that were cut short to fit the token budget.
+	Truncated []PlannedFile
+	// Omitted lists the planned files that were left out to fit the token budget.
+	Omitted []PlannedFile
+	// Deleted lists the planned files that were deleted in the git changes.
+	Deleted []PlannedFile
+	// Renamed lists the packed files that were renamed in the git changes.
+	Renamed []PlannedFile
+}
+
+// describeGeneration returns a one-line sentence describing when and from
+// which commit a document was generated.
+func describeGeneration(h *Header) string {
+	s := "Generated at " + h.GeneratedAt.UTC().Format(time.RFC3339)
+	if h.Commit != "" {
+		s += " from commit " + h.Commit
+	}
+	return s + "."
+}
+
+// describeTotals returns a one-line sentence summarizing the written files.
+func describeTotals(s Summary) string {
+	return fmt.Sprintf("Total: %d files, %d lines, %d bytes.", s.Files, s.Lines, s.Bytes)
+}
+
+// describeBudget returns plain-list paragraphs naming the files that were
+// truncated or omitted to fit the token budget, or nil if there are none.
+// The list syntax is shared by Markdown and Org Mode.
+func describeBudget(s Summary) []byte {
+	var out bytes.Buffer
+	if len(s.Truncated) > 0 {
+		out.WriteString("Truncated to fit the token budget:\n")
+		for _, file := range s.Truncated {
+			fmt.Fprintf(&out, "- %s\n", file.DisplayName())
+		}
+		out.WriteString("\n")
+	}
+	if len(s.Omitted) > 0 {
+		out.WriteString("Omitted to fit the token budget:\n")
+		for _, file := range s.Omitted {
+			fmt.Fprintf(&out, "- %s (~%d tokens)\n", file.Path, file.Tokens)
+		}
+		out.WriteString("\n")
+	}
+	return out.Bytes()
+}
+
+// describeChanges returns plain-list paragraphs naming the files that were
+// deleted or renamed in the git changes, or nil if there are none.
+// The list syntax is shared by Markdown and Org Mode.
+func describeChanges(s Summary) []byte {
+	var out bytes.Buffer
+	if len(s.Renamed) > 0 {
+		out.WriteString("Renamed:\n")
+		for _, file := range s.Renamed {
+			fmt.Fprintf(&out, "- %s (from %s)\n", file.Path, file.RenamedFrom)
+		}
+		out.WriteString("\n")
+	}
+	if len(s.Deleted) > 0 {
+		out.WriteString("Deleted:\n")
+		for _, file := range s.Deleted {
+			fmt.Fprintf(&out, "- %s\n", file.Path)
+		}
+		out.WriteString("\n")
+	}
+	return out.Bytes()
+}
+
+// describePart returns a one-line sentence numbering a split document, or
+// an empty string if the output is not split.
+func describePart(doc Document) string {
+	if doc.Parts == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Part %d of %d.", doc.Part, doc.Parts)
+}
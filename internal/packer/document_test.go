@@ -0,0 +1,156 @@
+package packer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPacker_ExecuteDocument(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go": "package a\n",
+		"b.go": "package b\n\nfunc B() {}\n",
+	}
+	var plan []PlannedFile
+	for _, name := range []string{"a.go", "b.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		plan = append(plan, PlannedFile{Path: path, Language: "go"})
+	}
+
+	header := &Header{
+		Title:       "demo",
+		GeneratedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Commit:      "abc1234",
+	}
+
+	testCases := []struct {
+		name      string
+		formatter Formatter
+		header    *Header
+		wantStart string
+		wantEnd   string
+	}{
+		{
+			name:      "markdown without header is unchanged",
+			formatter: NewMarkdownFormatter(),
+			wantStart: "- " + plan[0].Path + "\n",
+			wantEnd:   "```\n\n",
+		},
+		{
+			name:      "markdown with header",
+			formatter: NewMarkdownFormatter(),
+			header:    header,
+			wantStart: "# demo\n\nGenerated at 2024-01-02T03:04:05Z from commit abc1234.\n\n" +
+				"## Files\n\n1. " + plan[0].Path + "\n2. " + plan[1].Path + "\n\n- ",
+			wantEnd: "```\n\n---\n\nTotal: 2 files, 4 lines, 33 bytes.\n",
+		},
+		{
+			name:      "org with header",
+			formatter: NewOrgFormatter(),
+			header:    header,
+			wantStart: "#+TITLE: demo\nGenerated at 2024-01-02T03:04:05Z from commit abc1234.\n\n" +
+				"Files:\n1. " + plan[0].Path + "\n2. " + plan[1].Path + "\n\n- ",
+			wantEnd: "#+END_SRC\n\n-----\nTotal: 2 files, 4 lines, 33 bytes.\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			p := NewPacker(tc.formatter, &out, nil, nil, Options{Header: tc.header})
+			if err := p.Execute(plan); err != nil {
+				t.Fatalf("Execute() returned an unexpected error: %v", err)
+			}
+
+			got := out.String()
+			if !strings.HasPrefix(got, tc.wantStart) {
+				t.Errorf("output does not start with %q:\n%s", tc.wantStart, got)
+			}
+			if !strings.HasSuffix(got, tc.wantEnd) {
+				t.Errorf("output does not end with %q:\n%s", tc.wantEnd, got)
+			}
+		})
+	}
+}
+
+func TestPacker_ExecuteBudgetTrailer(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "kept.go")
+	if err := os.WriteFile(path, []byte("line 1\nline 2\nline 3\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	plan := []PlannedFile{
+		{Path: path, Language: "go", Range: &LineRange{Start: 1, End: 2}, Truncated: true},
+		{Path: "dropped.go", Language: "go", Tokens: 42, Omitted: true},
+	}
+
+	var out bytes.Buffer
+	p := NewPacker(NewMarkdownFormatter(), &out, nil, nil, Options{})
+	if err := p.Execute(plan); err != nil {
+		t.Fatalf("Execute() returned an unexpected error: %v", err)
+	}
+
+	want := "- " + path + ":1-2\n```go\nline 1\nline 2\n\n```\n\n" +
+		"---\n\n" +
+		"Truncated to fit the token budget:\n- " + path + ":1-2\n\n" +
+		"Omitted to fit the token budget:\n- dropped.go (~42 tokens)\n\n"
+	if got := out.String(); got != want {
+		t.Errorf("Execute() output mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPacker_ExecuteChangesTrailer(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "new.go")
+	if err := os.WriteFile(path, []byte("package a\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	plan := []PlannedFile{
+		{Path: path, Language: "go", RenamedFrom: "old.go"},
+		{Path: "gone.go", Deleted: true},
+	}
+
+	testCases := []struct {
+		name      string
+		formatter Formatter
+		want      string
+	}{
+		{
+			name:      "markdown",
+			formatter: NewMarkdownFormatter(),
+			want: "- " + path + "\n```go\npackage a\n\n```\n\n" +
+				"---\n\n" +
+				"Renamed:\n- " + path + " (from old.go)\n\n" +
+				"Deleted:\n- gone.go\n\n",
+		},
+		{
+			name:      "jsonl",
+			formatter: NewJSONLFormatter(),
+			want: `{"path":"` + path + `","language":"go","size":10,"lines":1,"content":"package a\n"}` + "\n" +
+				`{"path":"old.go","language":"go","size":0,"lines":0,"content":"","renamed_to":"` + path + `"}` + "\n" +
+				`{"path":"gone.go","language":"","size":0,"lines":0,"content":"","deleted":true}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			p := NewPacker(tc.formatter, &out, nil, nil, Options{})
+			if err := p.Execute(plan); err != nil {
+				t.Fatalf("Execute() returned an unexpected error: %v", err)
+			}
+			if got := out.String(); got != tc.want {
+				t.Errorf("Execute() output mismatch:\ngot:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
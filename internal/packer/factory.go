@@ -9,7 +9,13 @@ func NewFormatter(formatName string) (Formatter, error) {
 		return NewMarkdownFormatter(), nil
 	case "org":
 		return NewOrgFormatter(), nil
+	case "xml":
+		return NewXMLFormatter(), nil
+	case "json":
+		return NewJSONFormatter(), nil
+	case "jsonl":
+		return NewJSONLFormatter(), nil
 	default:
-		return nil, fmt.Errorf("unknown format: %q. Supported formats: markdown, md, org", formatName)
+		return nil, fmt.Errorf("unknown format: %q. Supported formats: markdown, md, org, xml, json, jsonl", formatName)
 	}
 }
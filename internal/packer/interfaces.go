@@ -1,6 +1,41 @@
 package packer
 
+import "io"
+
 // Formatter defines the contract for output formatters.
 type Formatter interface {
 	Format(filename, language string, content []byte) ([]byte, error)
 }
+
+// DocumentFormatter is implemented by formatters that write output around the
+// files themselves, such as an enclosing root element, a preamble or a trailer.
+// Begin is called once before the first file and End once after the last one.
+type DocumentFormatter interface {
+	Formatter
+	Begin(doc Document) ([]byte, error)
+	End(summary Summary) ([]byte, error)
+}
+
+// StreamFormatter is implemented by formatters that can copy a file from its
+// source straight to the output, so that its content is never held in memory
+// as a whole. Open may be called more than once, for instance to scan the
+// content before copying it, and returns the same content every time.
+type StreamFormatter interface {
+	Formatter
+	FormatStream(w io.Writer, filename, language string, open func() (io.ReadCloser, error)) error
+}
+
+// Transform rewrites the content of a planned file before it is formatted,
+// for example to remove comments. Content is already restricted to the
+// file's line range, if any.
+type Transform interface {
+	Apply(file PlannedFile, content []byte) ([]byte, error)
+}
+
+// StreamTransform is implemented by transforms that can also rewrite the
+// content of a file as it is read, so that files can be streamed to the
+// output with a StreamFormatter.
+type StreamTransform interface {
+	Transform
+	Stream(file PlannedFile, r io.Reader) io.Reader
+}
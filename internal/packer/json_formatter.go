@@ -0,0 +1,256 @@
+package packer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonRecord is the per-file object emitted by the JSON and JSONL formatters.
+type jsonRecord struct {
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Size     int    `json:"size"`
+	Lines    int    `json:"lines"`
+	Content  string `json:"content"`
+	// Omitted marks files left out by the token budget; they have no content.
+	Omitted bool `json:"omitted,omitempty"`
+	// Deleted marks files deleted in the git changes; they have no content.
+	Deleted bool `json:"deleted,omitempty"`
+	// RenamedTo is the current path of a file renamed in the git changes.
+	// The record is that of its previous path, which has no content.
+	RenamedTo string `json:"renamed_to,omitempty"`
+}
+
+// encodeJSONRecord marshals a file as a single-line JSON object terminated by a newline.
+func encodeJSONRecord(filename, language string, content []byte) ([]byte, error) {
+	return encodeJSON(jsonRecord{
+		Path:     filename,
+		Language: language,
+		Size:     len(content),
+		Lines:    countLines(content),
+		Content:  string(content),
+	})
+}
+
+// streamJSONRecord writes separator and the same object as encodeJSONRecord,
+// without its final newline, reading the content twice: once to count its
+// size and lines and once to copy it to w, escaped as a JSON string.
+func streamJSONRecord(w io.Writer, separator []byte, filename, language string, open func() (io.ReadCloser, error)) error {
+	r, err := open()
+	if err != nil {
+		return err
+	}
+	counter := &lineCounter{Reader: r, Closer: r}
+	_, err = io.Copy(io.Discard, counter)
+	r.Close()
+	if err != nil {
+		return err
+	}
+
+	// The content is the last field set, so the record without it ends
+	// with the empty string.
+	record, err := encodeJSON(jsonRecord{
+		Path:     filename,
+		Language: language,
+		Size:     int(counter.bytes),
+		Lines:    counter.lines(),
+	})
+	if err != nil {
+		return err
+	}
+	prefix, ok := bytes.CutSuffix(record, []byte(`""}`+"\n"))
+	if !ok {
+		return fmt.Errorf("unexpected JSON record %q", record)
+	}
+	if _, err := w.Write(append(append(separator, prefix...), '"')); err != nil {
+		return err
+	}
+
+	content := &runeWriter{write: func(p []byte) error {
+		escaped, err := encodeJSONString(p)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(escaped)
+		return err
+	}}
+	if err := copyFrom(content, open); err != nil {
+		return err
+	}
+	if err := content.Close(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, `"}`)
+	return err
+}
+
+// encodeJSONString returns the characters of content escaped as in a JSON
+// string, without the quotes, as encodeJSON escapes them.
+func encodeJSONString(content []byte) ([]byte, error) {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(string(content)); err != nil {
+		return nil, err
+	}
+	escaped := bytes.TrimSuffix(out.Bytes(), []byte{'\n'})
+	return escaped[1 : len(escaped)-1], nil
+}
+
+// encodeTrailerRecords marshals the files listed in a summary that have no
+// content: files omitted by the token budget, and files deleted or renamed
+// in the git changes. A renamed file is recorded under its previous path.
+func encodeTrailerRecords(summary Summary) ([][]byte, error) {
+	var records []jsonRecord
+	for _, file := range summary.Omitted {
+		records = append(records, jsonRecord{Path: file.Path, Language: file.Language, Omitted: true})
+	}
+	for _, file := range summary.Renamed {
+		records = append(records, jsonRecord{Path: file.RenamedFrom, Language: file.Language, RenamedTo: file.Path})
+	}
+	for _, file := range summary.Deleted {
+		records = append(records, jsonRecord{Path: file.Path, Deleted: true})
+	}
+
+	encoded := make([][]byte, 0, len(records))
+	for _, record := range records {
+		b, err := encodeJSON(record)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, b)
+	}
+	return encoded, nil
+}
+
+// encodeJSON marshals a record on a single line without HTML escaping.
+func encodeJSON(record jsonRecord) ([]byte, error) {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(record); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// countLines returns the number of lines in content, counting a final
+// line that lacks a trailing newline.
+func countLines(content []byte) int {
+	n := bytes.Count(content, []byte{'\n'})
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// JSONFormatter implements the DocumentFormatter interface, emitting all
+// files as a single JSON array of objects.
+type JSONFormatter struct {
+	count int
+}
+
+// NewJSONFormatter creates a new JSONFormatter.
+func NewJSONFormatter() *JSONFormatter {
+	return &JSONFormatter{}
+}
+
+// clone returns a copy of the formatter with the same element count.
+func (f *JSONFormatter) clone() Formatter {
+	c := *f
+	return &c
+}
+
+// Begin opens the JSON array and resets the element count.
+func (f *JSONFormatter) Begin(doc Document) ([]byte, error) {
+	f.count = 0
+	return []byte("["), nil
+}
+
+// End appends records for files without content, such as those left out
+// by the token budget, and closes the JSON array.
+func (f *JSONFormatter) End(summary Summary) ([]byte, error) {
+	records, err := encodeTrailerRecords(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	for _, record := range records {
+		out = f.appendElement(out, record)
+	}
+
+	if f.count == 0 {
+		return append(out, "]\n"...), nil
+	}
+	return append(out, "\n]\n"...), nil
+}
+
+// Format returns the file as a JSON object, prefixed with the separator
+// required by its position in the array.
+func (f *JSONFormatter) Format(filename, language string, content []byte) ([]byte, error) {
+	record, err := encodeJSONRecord(filename, language, content)
+	if err != nil {
+		return nil, err
+	}
+	return f.appendElement(nil, record), nil
+}
+
+// FormatStream writes the same array element as Format, reading the
+// content twice: once to count its size and lines and once to copy it to w.
+func (f *JSONFormatter) FormatStream(w io.Writer, filename, language string, open func() (io.ReadCloser, error)) error {
+	return streamJSONRecord(w, f.appendElement(nil, nil), filename, language, open)
+}
+
+// appendElement appends an encoded record to out as the next array element.
+func (f *JSONFormatter) appendElement(out, record []byte) []byte {
+	separator := ",\n"
+	if f.count == 0 {
+		separator = "\n"
+	}
+	f.count++
+
+	out = append(out, separator...)
+	return append(out, bytes.TrimSuffix(record, []byte{'\n'})...)
+}
+
+// JSONLFormatter implements the DocumentFormatter interface, emitting one
+// JSON object per line.
+type JSONLFormatter struct{}
+
+// NewJSONLFormatter creates a new JSONLFormatter.
+func NewJSONLFormatter() *JSONLFormatter {
+	return &JSONLFormatter{}
+}
+
+// Begin writes nothing, as JSON Lines has no enclosing structure.
+func (f *JSONLFormatter) Begin(doc Document) ([]byte, error) {
+	return nil, nil
+}
+
+// End appends one line for each file without content, such as those left
+// out by the token budget.
+func (f *JSONLFormatter) End(summary Summary) ([]byte, error) {
+	records, err := encodeTrailerRecords(summary)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Join(records, nil), nil
+}
+
+// Format returns the file as a single line of JSON.
+func (f *JSONLFormatter) Format(filename, language string, content []byte) ([]byte, error) {
+	return encodeJSONRecord(filename, language, content)
+}
+
+// FormatStream writes the same line as Format, reading the content twice:
+// once to count its size and lines and once to copy it to w.
+func (f *JSONLFormatter) FormatStream(w io.Writer, filename, language string, open func() (io.ReadCloser, error)) error {
+	if err := streamJSONRecord(w, nil, filename, language, open); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,108 @@
+package packer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// testFile is a minimal file description used to drive formatters in tests.
+type testFile struct {
+	name, language, content string
+}
+
+func TestJSONFormatter(t *testing.T) {
+	testCases := []struct {
+		name  string
+		files []testFile
+		want  []jsonRecord
+	}{
+		{
+			name:  "empty plan produces an empty array",
+			files: nil,
+			want:  []jsonRecord{},
+		},
+		{
+			name:  "single file",
+			files: []testFile{{"main.go", "go", "package main\n"}},
+			want: []jsonRecord{
+				{Path: "main.go", Language: "go", Size: 13, Lines: 1, Content: "package main\n"},
+			},
+		},
+		{
+			name: "multiple files with special characters",
+			files: []testFile{
+				{"a.html", "html", "<b>\"hi\"</b>\n\tx"},
+				{"empty.txt", "text", ""},
+			},
+			want: []jsonRecord{
+				{Path: "a.html", Language: "html", Size: 14, Lines: 2, Content: "<b>\"hi\"</b>\n\tx"},
+				{Path: "empty.txt", Language: "text", Size: 0, Lines: 0, Content: ""},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := NewJSONFormatter()
+			var out bytes.Buffer
+
+			begin, _ := f.Begin(Document{})
+			out.Write(begin)
+			for _, file := range tc.files {
+				formatted, err := f.Format(file.name, file.language, []byte(file.content))
+				if err != nil {
+					t.Fatalf("Format() returned an unexpected error: %v", err)
+				}
+				out.Write(formatted)
+			}
+			end, _ := f.End(Summary{})
+			out.Write(end)
+
+			got := []jsonRecord{}
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("output is not a valid JSON array: %v\noutput:\n%s", err, out.String())
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("records mismatch:\ngot:  %+v\nwant: %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJSONLFormatter(t *testing.T) {
+	files := []testFile{
+		{"main.go", "go", "package main\n\nfunc main() {}\n"},
+		{"notes.txt", "text", "line one\nline two"},
+	}
+
+	f := NewJSONLFormatter()
+	var out bytes.Buffer
+	for _, file := range files {
+		formatted, err := f.Format(file.name, file.language, []byte(file.content))
+		if err != nil {
+			t.Fatalf("Format() returned an unexpected error: %v", err)
+		}
+		out.Write(formatted)
+	}
+
+	var got []jsonRecord
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var record jsonRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %q is not valid JSON: %v", scanner.Text(), err)
+		}
+		got = append(got, record)
+	}
+
+	want := []jsonRecord{
+		{Path: "main.go", Language: "go", Size: 29, Lines: 3, Content: "package main\n\nfunc main() {}\n"},
+		{Path: "notes.txt", Language: "text", Size: 17, Lines: 2, Content: "line one\nline two"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("records mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package packer
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// LineRange selects the lines Start through End of a file. Both bounds are
+// 1-based and inclusive.
+type LineRange struct {
+	Start, End int
+}
+
+// String returns the range in "start-end" notation.
+func (r LineRange) String() string {
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// Slice returns the lines of content covered by the range, including the
+// newline of the last selected line. Bounds beyond the content are clamped.
+func (r LineRange) Slice(content []byte) []byte {
+	start := lineOffset(content, r.Start)
+	end := lineOffset(content, r.End+1)
+	return content[start:end]
+}
+
+// lineOffset returns the byte offset at which the given 1-based line starts,
+// or len(content) if the content has fewer lines.
+func lineOffset(content []byte, line int) int {
+	offset := 0
+	for n := 1; n < line; n++ {
+		i := bytes.IndexByte(content[offset:], '\n')
+		if i < 0 {
+			return len(content)
+		}
+		offset += i + 1
+	}
+	return offset
+}
+
+// numberLines prefixes every line of content with its line number, counting
+// from first. Numbers are right-aligned to the width of the largest one so
+// that the code stays aligned. Empty lines carry no trailing space.
+func numberLines(content []byte, first int) []byte {
+	lines := countLines(content)
+	if lines == 0 {
+		return content
+	}
+	width := len(strconv.Itoa(first + lines - 1))
+
+	out := make([]byte, 0, len(content)+lines*(width+3))
+	for n := first; len(content) > 0; n++ {
+		line := content
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			line = content[:i+1]
+		}
+		content = content[len(line):]
+
+		out = fmt.Appendf(out, "%*d |", width, n)
+		if text := bytes.TrimRight(line, "\r\n"); len(text) > 0 {
+			out = append(out, ' ')
+		}
+		out = append(out, line...)
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package packer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLineRange_Slice(t *testing.T) {
+	content := []byte("one\ntwo\nthree\nfour")
+
+	testCases := []struct {
+		r    LineRange
+		want string
+	}{
+		{LineRange{Start: 1, End: 1}, "one\n"},
+		{LineRange{Start: 2, End: 3}, "two\nthree\n"},
+		{LineRange{Start: 3, End: 4}, "three\nfour"},
+		{LineRange{Start: 3, End: 99}, "three\nfour"},
+		{LineRange{Start: 10, End: 12}, ""},
+	}
+
+	for _, tc := range testCases {
+		if got := string(tc.r.Slice(content)); got != tc.want {
+			t.Errorf("LineRange%v.Slice() = %q, want %q", tc.r, got, tc.want)
+		}
+	}
+}
+
+func TestNumberLines(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		first   int
+		want    string
+	}{
+		{
+			name:    "empty content",
+			content: "",
+			first:   1,
+			want:    "",
+		},
+		{
+			name:    "numbers are padded to the widest number",
+			content: strings.Repeat("x\n", 10),
+			first:   1,
+			want: " 1 | x\n 2 | x\n 3 | x\n 4 | x\n 5 | x\n" +
+				" 6 | x\n 7 | x\n 8 | x\n 9 | x\n10 | x\n",
+		},
+		{
+			name:    "blank lines have no trailing space",
+			content: "a\n\nb",
+			first:   1,
+			want:    "1 | a\n2 |\n3 | b",
+		},
+		{
+			name:    "range starts at its first line",
+			content: "a\nb\n",
+			first:   99,
+			want:    " 99 | a\n100 | b\n",
+		},
+		{
+			name:    "crlf line endings are preserved",
+			content: "a\r\n\r\n",
+			first:   1,
+			want:    "1 | a\r\n2 |\r\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := string(numberLines([]byte(tc.content), tc.first)); got != tc.want {
+				t.Errorf("numberLines() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPacker_ExecuteLineNumbers(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.org")
+	content := "* Heading\n#+BEGIN_SRC go\n,x\n#+END_SRC\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	plan := []PlannedFile{{Path: path, Language: "org", Range: &LineRange{Start: 2, End: 4}}}
+
+	testCases := []struct {
+		name      string
+		formatter Formatter
+		want      string
+	}{
+		{
+			name:      "org lines no longer need comma escaping",
+			formatter: NewOrgFormatter(),
+			want: "- " + path + ":2-4\n#+BEGIN_SRC org\n" +
+				"2 | #+BEGIN_SRC go\n3 | ,x\n4 | #+END_SRC\n#+END_SRC\n\n",
+		},
+		{
+			name:      "markdown keeps the default fence",
+			formatter: NewMarkdownFormatter(),
+			want: "- " + path + ":2-4\n```org\n" +
+				"2 | #+BEGIN_SRC go\n3 | ,x\n4 | #+END_SRC\n\n```\n\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			p := NewPacker(tc.formatter, &out, nil, nil, Options{LineNumbers: true})
+			if err := p.Execute(plan); err != nil {
+				t.Fatalf("Execute() returned an unexpected error: %v", err)
+			}
+			if got := out.String(); got != tc.want {
+				t.Errorf("Execute() output mismatch:\ngot:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
@@ -3,9 +3,14 @@ package packer
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"strings"
 )
 
-// MarkdownFormatter implements the Formatter interface for Markdown.
+// minFenceLength is the shortest code fence allowed by CommonMark.
+const minFenceLength = 3
+
+// MarkdownFormatter implements the DocumentFormatter interface for Markdown.
 type MarkdownFormatter struct{}
 
 // NewMarkdownFormatter creates a new MarkdownFormatter.
@@ -13,7 +18,56 @@ func NewMarkdownFormatter() *MarkdownFormatter {
 	return &MarkdownFormatter{}
 }
 
+// Begin renders the optional preamble: the part number of a split
+// document, a title, the generation metadata, a numbered table of contents
+// and a directory tree.
+func (f *MarkdownFormatter) Begin(doc Document) ([]byte, error) {
+	var out bytes.Buffer
+
+	if part := describePart(doc); part != "" {
+		fmt.Fprintf(&out, "%s\n\n", part)
+	}
+
+	if doc.Header != nil {
+		fmt.Fprintf(&out, "# %s\n\n%s\n\n", doc.Header.Title, describeGeneration(doc.Header))
+
+		if len(doc.Files) > 0 {
+			out.WriteString("## Files\n\n")
+			for i, file := range doc.Files {
+				fmt.Fprintf(&out, "%d. %s\n", i+1, file.Path)
+			}
+			out.WriteString("\n")
+		}
+	}
+
+	if doc.Tree != nil {
+		tree := renderASCIITree(doc.Tree)
+		fence := codeFence(tree)
+		fmt.Fprintf(&out, "%stext\n%s%s\n\n", fence, tree, fence)
+	}
+	return out.Bytes(), nil
+}
+
+// End renders the files left out by the token budget, the files deleted or
+// renamed in the git changes, and the optional closing summary after a
+// horizontal rule.
+func (f *MarkdownFormatter) End(summary Summary) ([]byte, error) {
+	notes := append(describeBudget(summary), describeChanges(summary)...)
+	if summary.Header == nil && len(notes) == 0 {
+		return nil, nil
+	}
+
+	var out bytes.Buffer
+	out.WriteString("---\n\n")
+	out.Write(notes)
+	if summary.Header != nil {
+		fmt.Fprintf(&out, "%s\n", describeTotals(summary))
+	}
+	return out.Bytes(), nil
+}
+
 // Format takes file details and content, and returns it formatted as a Markdown code block.
+// The fence is chosen to be longer than any backtick run in the content.
 func (f *MarkdownFormatter) Format(filename, language string, content []byte) ([]byte, error) {
 	var out bytes.Buffer
 
@@ -21,7 +75,8 @@ func (f *MarkdownFormatter) Format(filename, language string, content []byte) ([
 		return nil, err
 	}
 
-	if _, err := fmt.Fprintf(&out, "```%s\n", language); err != nil {
+	fence := codeFence(content)
+	if _, err := fmt.Fprintf(&out, "%s%s\n", fence, language); err != nil {
 		return nil, err
 	}
 
@@ -29,9 +84,69 @@ func (f *MarkdownFormatter) Format(filename, language string, content []byte) ([
 		return nil, err
 	}
 
-	if _, err := out.WriteString("\n```\n\n"); err != nil {
+	if _, err := fmt.Fprintf(&out, "\n%s\n\n", fence); err != nil {
 		return nil, err
 	}
 
 	return out.Bytes(), nil
 }
+
+// FormatStream writes the same code block as Format, reading the content
+// twice: once to choose the fence and once to copy it to w.
+func (f *MarkdownFormatter) FormatStream(w io.Writer, filename, language string, open func() (io.ReadCloser, error)) error {
+	var runs backtickRuns
+	if err := copyFrom(&runs, open); err != nil {
+		return err
+	}
+	fence := runs.fence()
+
+	if _, err := fmt.Fprintf(w, "- %s\n%s%s\n", filename, fence, language); err != nil {
+		return err
+	}
+	if err := copyFrom(w, open); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%s\n\n", fence)
+	return err
+}
+
+// codeFence returns a backtick fence that is longer than the longest run of
+// backticks in content, so no line of the content can close the code block early.
+func codeFence(content []byte) string {
+	var runs backtickRuns
+	runs.Write(content)
+	return runs.fence()
+}
+
+// backtickRuns tracks the longest run of backticks in the content written to it.
+type backtickRuns struct {
+	longest, run int
+}
+
+func (r *backtickRuns) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b != '`' {
+			r.run = 0
+			continue
+		}
+		r.run++
+		r.longest = max(r.longest, r.run)
+	}
+	return len(p), nil
+}
+
+// fence returns a backtick fence longer than the longest run.
+func (r *backtickRuns) fence() string {
+	return strings.Repeat("`", max(minFenceLength, r.longest+1))
+}
+
+// copyFrom opens content and copies it to w.
+func copyFrom(w io.Writer, open func() (io.ReadCloser, error)) error {
+	r, err := open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
@@ -0,0 +1,72 @@
+package packer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownFormatter_Fence(t *testing.T) {
+	testCases := []struct {
+		name      string
+		content   string
+		wantFence string
+	}{
+		{
+			name:      "plain source uses the default fence",
+			content:   "package main\n\nfunc main() {}\n",
+			wantFence: "```",
+		},
+		{
+			name:      "inline code spans do not need a longer fence",
+			content:   "Use `go test` to run ``tests``.\n",
+			wantFence: "```",
+		},
+		{
+			name:      "readme with a fenced example",
+			content:   "# Title\n\n```sh\nmake build\n```\n",
+			wantFence: "````",
+		},
+		{
+			name:      "nested fences",
+			content:   "`````md\n````go\n```\n````\n`````\n",
+			wantFence: "``````",
+		},
+		{
+			name:      "go raw string containing a fence",
+			content:   "package x\n\nconst doc = `\n```\nexample\n```\n`\n",
+			wantFence: "````",
+		},
+		{
+			name:      "crlf content",
+			content:   "intro\r\n```\r\ncode\r\n```\r\n",
+			wantFence: "````",
+		},
+	}
+
+	f := NewMarkdownFormatter()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := f.Format("file", "text", []byte(tc.content))
+			if err != nil {
+				t.Fatalf("Format() returned an unexpected error: %v", err)
+			}
+
+			lines := strings.Split(string(out), "\n")
+			if got := strings.TrimSuffix(lines[1], "text"); got != tc.wantFence {
+				t.Fatalf("opening fence = %q, want %q", got, tc.wantFence)
+			}
+
+			// The block must only be closed by the final fence line.
+			body := lines[2 : len(lines)-3]
+			for i, line := range body {
+				trimmed := strings.TrimLeft(strings.TrimRight(line, "\r"), " ")
+				if strings.HasPrefix(trimmed, tc.wantFence) {
+					t.Errorf("content line %d %q would close the %q fence", i+1, line, tc.wantFence)
+				}
+			}
+			if closing := lines[len(lines)-3]; closing != tc.wantFence {
+				t.Errorf("closing fence = %q, want %q", closing, tc.wantFence)
+			}
+		})
+	}
+}
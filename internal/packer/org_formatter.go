@@ -4,9 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 )
 
-// OrgFormatter implements the Formatter interface for Org Mode.
+// OrgFormatter implements the DocumentFormatter interface for Org Mode.
 type OrgFormatter struct{}
 
 // NewOrgFormatter creates a new OrgFormatter.
@@ -14,6 +15,54 @@ func NewOrgFormatter() *OrgFormatter {
 	return &OrgFormatter{}
 }
 
+// Begin renders the optional preamble: the part number of a split
+// document, a #+TITLE keyword, the generation metadata, a numbered list of
+// files and a directory tree.
+func (f *OrgFormatter) Begin(doc Document) ([]byte, error) {
+	var out bytes.Buffer
+
+	if part := describePart(doc); part != "" {
+		fmt.Fprintf(&out, "%s\n\n", part)
+	}
+
+	if doc.Header != nil {
+		fmt.Fprintf(&out, "#+TITLE: %s\n%s\n\n", doc.Header.Title, describeGeneration(doc.Header))
+
+		if len(doc.Files) > 0 {
+			out.WriteString("Files:\n")
+			for i, file := range doc.Files {
+				fmt.Fprintf(&out, "%d. %s\n", i+1, file.Path)
+			}
+			out.WriteString("\n")
+		}
+	}
+
+	if doc.Tree != nil {
+		out.WriteString("Project tree:\n")
+		out.Write(renderListTree(doc.Tree))
+		out.WriteString("\n")
+	}
+	return out.Bytes(), nil
+}
+
+// End renders the files left out by the token budget, the files deleted or
+// renamed in the git changes, and the optional closing summary after a
+// horizontal rule.
+func (f *OrgFormatter) End(summary Summary) ([]byte, error) {
+	notes := append(describeBudget(summary), describeChanges(summary)...)
+	if summary.Header == nil && len(notes) == 0 {
+		return nil, nil
+	}
+
+	var out bytes.Buffer
+	out.WriteString("-----\n")
+	out.Write(notes)
+	if summary.Header != nil {
+		fmt.Fprintf(&out, "%s\n", describeTotals(summary))
+	}
+	return out.Bytes(), nil
+}
+
 // escapeOrgContent prepends a comma to lines within an Org source block
 // that could be misinterpreted by the Org parser, such as headings or directives.
 // This is the standard way to escape content within a source block.
@@ -74,3 +123,56 @@ func (f *OrgFormatter) Format(filename, language string, content []byte) ([]byte
 
 	return out.Bytes(), nil
 }
+
+// FormatStream writes the same source block as Format, copying the content
+// to w as it is read. Org files, whose lines may need escaping, are read
+// into memory and formatted by Format.
+func (f *OrgFormatter) FormatStream(w io.Writer, filename, language string, open func() (io.ReadCloser, error)) error {
+	r, err := open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if language == "org" {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		formatted, err := f.Format(filename, language, content)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(formatted)
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "- %s\n#+BEGIN_SRC %s\n", filename, language); err != nil {
+		return err
+	}
+	last := &lastByteWriter{w: w, last: '\n'}
+	if _, err := io.Copy(last, r); err != nil {
+		return err
+	}
+	if last.last != '\n' {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "#+END_SRC\n\n")
+	return err
+}
+
+// lastByteWriter passes writes through to w, remembering the last byte written.
+type lastByteWriter struct {
+	w    io.Writer
+	last byte
+}
+
+func (l *lastByteWriter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	if n > 0 {
+		l.last = p[n-1]
+	}
+	return n, err
+}
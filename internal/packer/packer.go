@@ -1,17 +1,23 @@
 package packer
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/user"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 
-	"github.com/bmatcuk/doublestar/v4"
 	"github.com/jbwfu/syntex/internal/filter"
 	"github.com/jbwfu/syntex/internal/language"
+	"github.com/jbwfu/syntex/internal/project"
+	"github.com/jbwfu/syntex/internal/source"
+	"github.com/jbwfu/syntex/internal/token"
 )
 
 // PlannedFile holds pre-calculated information for a file to be processed.
@@ -20,6 +26,123 @@ type PlannedFile struct {
 	Path string
 	// Language is the detected language identifier for syntax highlighting.
 	Language string
+	// Explicit is true if the file was named directly rather than matched
+	// by a glob pattern or a directory target.
+	Explicit bool
+	// Range restricts the output to a span of lines. It is nil for whole files.
+	Range *LineRange
+	// Piece restricts the output to a span of lines of the content after
+	// transforms, counted from the start of Range. It is set for files cut
+	// short to fit the token budget or split across parts, so that every
+	// transform still sees the whole selection.
+	Piece *LineRange
+	// Symbol is the Go declaration named by a "path#symbol" target, if any.
+	Symbol string
+	// Tokens is the estimated token count of the content, set by CountTokens.
+	Tokens int
+	// Truncated is true if Piece was set to fit the token budget.
+	Truncated bool
+	// Omitted is true if the file was dropped to fit the token budget.
+	// Omitted files are listed in the document trailer instead of being packed.
+	Omitted bool
+	// Deleted is true if the file was deleted in the git changes the plan is
+	// restricted to. Deleted files are listed in the document trailer, and
+	// with a Differ, their removal diff is packed.
+	Deleted bool
+	// RenamedFrom is the previous path of a file renamed in the git changes.
+	RenamedFrom string
+}
+
+// DisplayName returns the path shown in the output, including the symbol
+// and line range if any, e.g. "main.go#run:40-120". The range of a piece
+// counts the lines of the packed content, which are those of the file
+// unless a transform removed or added lines.
+func (f PlannedFile) DisplayName() string {
+	name := f.Path
+	if f.Symbol != "" {
+		name += "#" + f.Symbol
+	}
+	switch {
+	case f.Piece != nil:
+		first := f.firstLine()
+		name += ":" + LineRange{Start: first + f.Piece.Start - 1, End: first + f.Piece.End - 1}.String()
+	case f.Range != nil:
+		name += ":" + f.Range.String()
+	}
+	return name
+}
+
+// firstLine returns the number of the first selected line in the file.
+func (f PlannedFile) firstLine() int {
+	if f.Range != nil {
+		return f.Range.Start
+	}
+	return 1
+}
+
+// candidate is a file discovered during planning that passed all filters.
+type candidate struct {
+	// path is the original path from user input or glob match.
+	path     string
+	absPath  string
+	explicit bool
+	// lines and symbol hold the selector of a "path:start-end" or
+	// "path#symbol" target.
+	lines  *LineRange
+	symbol string
+}
+
+// key identifies a candidate in the plan. The same file may appear more
+// than once if it was targeted with different selectors.
+func (c candidate) key() string {
+	if c.lines == nil {
+		return c.absPath
+	}
+	return fmt.Sprintf("%s#%s:%s", c.absPath, c.symbol, c.lines)
+}
+
+// Options configures the optional behavior of a Packer.
+type Options struct {
+	// Header, when non-nil, asks formatters to render a preamble and a closing summary.
+	Header *Header
+	// Tree renders a directory tree of all planned files before their contents.
+	Tree bool
+	// LineNumbers prefixes every packed line with its line number in the file.
+	LineNumbers bool
+	// Transforms rewrite the content of every file, in order, before it is
+	// formatted and before line numbers are added. Without line numbers or
+	// a diff, and if every transform is a StreamTransform, a StreamFormatter
+	// copies whole files to the output as they are read.
+	Transforms []Transform
+	// Tokenizer estimates token counts. If nil, a token.Estimator is used.
+	Tokenizer token.Counter
+	// Source lists and reads the files to pack. If nil, the local filesystem is used.
+	Source source.Source
+	// Jobs is the number of files analyzed concurrently while planning.
+	// Zero means runtime.GOMAXPROCS(0).
+	Jobs int
+
+	// MaxTokens is the token budget of the pack. Zero means unlimited.
+	MaxTokens int
+	// PriorityGlobs lists patterns whose matches are kept first under the
+	// budget, in the given order.
+	PriorityGlobs []string
+	// SmallestFirst prefers smaller files over path order under the budget.
+	SmallestFirst bool
+	// Truncate cuts the first file exceeding the remaining budget short
+	// instead of omitting it.
+	Truncate bool
+
+	// Changes, when non-nil, restricts the plan to the files changed in git.
+	// Without targets, every changed file is planned. Deleted files matching
+	// the targets are planned as well, and renamed files note their old path.
+	Changes []project.Change
+	// Diff, when non-nil, packs the unified diff of every file against a git
+	// revision, as a block in the "diff" language, instead of its content.
+	// Deleted files are packed as their removal diff.
+	Diff *project.Differ
+	// DiffWithContent packs the content of every file before its diff.
+	DiffWithContent bool
 }
 
 // Packer handles the logic of discovering, filtering, and planning which files
@@ -29,6 +152,7 @@ type Packer struct {
 	output    io.Writer
 	filter    *filter.Manager
 	detector  *language.Detector
+	opts      Options
 }
 
 // NewPacker creates a new Packer instance with all its dependencies injected.
@@ -37,19 +161,47 @@ func NewPacker(
 	out io.Writer,
 	filter *filter.Manager,
 	detector *language.Detector,
+	opts Options,
 ) *Packer {
 	return &Packer{
 		formatter: f,
 		output:    out,
 		filter:    filter,
 		detector:  detector,
+		opts:      opts,
+	}
+}
+
+// tokenizer returns the configured token counter or the default estimator.
+func (p *Packer) tokenizer() token.Counter {
+	if p.opts.Tokenizer != nil {
+		return p.opts.Tokenizer
 	}
+	return token.NewEstimator()
+}
+
+// source returns the configured file source or the local filesystem.
+func (p *Packer) source() source.Source {
+	if p.opts.Source != nil {
+		return p.opts.Source
+	}
+	return source.NewOS()
 }
 
 // Plan discovers and filters files based on include patterns and target paths.
 // It returns a sorted slice of files that are ready to be processed.
 func (p *Packer) Plan(targets []string) ([]PlannedFile, error) {
-	uniqueFiles := make(map[string]string)
+	uniqueFiles := make(map[string]candidate)
+
+	changes := newChangeSet(p.opts.Changes)
+	planAllChanges := changes != nil && len(targets) == 0
+	if planAllChanges {
+		// Changed paths are added as they are, as they may contain glob
+		// metacharacters, as in "app/[id]/page.tsx".
+		for _, path := range changes.paths() {
+			p.addFileToPlan(path, path, uniqueFiles, false, candidate{explicit: true})
+		}
+	}
 
 	for _, pattern := range p.filter.GetIncludePatterns() {
 		if err := p.processPattern(pattern, uniqueFiles, true); err != nil {
@@ -63,11 +215,25 @@ func (p *Packer) Plan(targets []string) ([]PlannedFile, error) {
 		}
 	}
 
-	result := make([]PlannedFile, 0, len(uniqueFiles))
-	for absPath, originalPath := range uniqueFiles {
-		analysisResult, err := p.detector.AnalyzeFile(absPath)
+	candidates := make([]candidate, 0, len(uniqueFiles))
+	for _, c := range uniqueFiles {
+		if changes != nil {
+			if _, ok := changes.present[c.absPath]; !ok {
+				continue
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].key() < candidates[j].key()
+	})
+
+	analyses := p.analyzeFiles(candidates)
+	result := make([]PlannedFile, 0, len(candidates))
+	for i, c := range candidates {
+		analysisResult, err := analyses[i].result, analyses[i].err
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "warning: skipping unreadable file %s: %v\n", originalPath, err)
+			fmt.Fprintf(os.Stderr, "warning: skipping unreadable file %s: %v\n", c.path, err)
 			continue
 		}
 
@@ -75,63 +241,394 @@ func (p *Packer) Plan(targets []string) ([]PlannedFile, error) {
 			continue
 		}
 
+		var renamedFrom string
+		if changes != nil && changes.present[c.absPath].Status == project.Renamed {
+			renamedFrom = displayPath(changes.present[c.absPath].OldPath)
+		}
+
 		result = append(result, PlannedFile{
-			Path:     originalPath,
+			Path:     c.path,
 			Language: analysisResult.Language,
+			Explicit: c.explicit,
+			Range:    c.lines,
+			Symbol:   c.symbol,
+
+			RenamedFrom: renamedFrom,
 		})
 	}
 
-	sort.Slice(result, func(i, j int) bool {
+	if changes != nil {
+		patterns := slices.Concat(p.filter.GetIncludePatterns(), targets)
+		result = append(result, p.deletedFiles(changes, patterns, planAllChanges)...)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
 		return result[i].Path < result[j].Path
 	})
 
+	if p.opts.MaxTokens > 0 {
+		p.applyBudget(result)
+	}
+
 	return result, nil
 }
 
+// analysis is the outcome of analyzing a candidate file.
+type analysis struct {
+	result *language.DetectionResult
+	err    error
+}
+
+// analyzeFiles detects the language and binary status of the candidates on
+// a pool of Options.Jobs workers. The analyses are in the order of candidates.
+func (p *Packer) analyzeFiles(candidates []candidate) []analysis {
+	analyses := make([]analysis, len(candidates))
+	jobs := p.opts.Jobs
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	jobs = min(jobs, len(candidates))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result, err := p.detector.AnalyzeFile(candidates[i].absPath)
+				analyses[i] = analysis{result: result, err: err}
+			}
+		}()
+	}
+	for i := range candidates {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return analyses
+}
+
 // Execute processes a list of PlannedFile items, formats them using the
 // configured formatter, and writes the result to the output writer.
+// If the formatter is a DocumentFormatter, its Begin and End output
+// surrounds the formatted files, and omitted files are reported to End.
 func (p *Packer) Execute(plan []PlannedFile) error {
+	included, omitted := p.partitionPlan(plan)
+	return p.writeDocument(p.output, p.newDocument(included), omitted)
+}
+
+// partitionPlan separates the files to be packed from those omitted by the
+// token budget or deleted, which are only listed in the trailer. With a
+// Differ, deleted files are packed as their removal diff.
+func (p *Packer) partitionPlan(plan []PlannedFile) (included, omitted []PlannedFile) {
 	for _, file := range plan {
-		content, err := os.ReadFile(file.Path)
+		if file.Omitted || (file.Deleted && !p.packsDeleted()) {
+			omitted = append(omitted, file)
+		} else {
+			included = append(included, file)
+		}
+	}
+	return included, omitted
+}
+
+// packsDeleted reports whether deleted files are packed, as their removal
+// diff, rather than only listed in the trailer.
+func (p *Packer) packsDeleted() bool {
+	return p.opts.Diff != nil
+}
+
+// newDocument describes a document containing files, according to the
+// packer options. The tree leaves out deleted files.
+func (p *Packer) newDocument(files []PlannedFile) Document {
+	doc := Document{Header: p.opts.Header, Files: files}
+	if p.opts.Tree {
+		existing := slices.DeleteFunc(slices.Clone(files), func(file PlannedFile) bool {
+			return file.Deleted
+		})
+		doc.Tree = buildTree(p.source(), existing)
+	}
+	return doc
+}
+
+// writeDocument formats the files of doc and writes them to w, surrounded by
+// the formatter's Begin and End output if it is a DocumentFormatter.
+func (p *Packer) writeDocument(w io.Writer, doc Document, omitted []PlannedFile) error {
+	docFormatter, isDocument := p.formatter.(DocumentFormatter)
+	if isDocument {
+		begin, err := docFormatter.Begin(doc)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "warning: skipping unreadable file %s: %v\n", file.Path, err)
+			return fmt.Errorf("formatting document header: %w", err)
+		}
+		if _, err := w.Write(begin); err != nil {
+			return fmt.Errorf("writing document header: %w", err)
+		}
+	}
+
+	summary := p.newSummary(omitted)
+	for _, file := range doc.Files {
+		if streamer, ok := p.streamFormatter(file); ok {
+			lines, size, err := p.streamFile(w, streamer, file)
+			var unreadable *unreadableError
+			if errors.As(err, &unreadable) {
+				fmt.Fprintf(os.Stderr, "warning: skipping unreadable file %s: %v\n", file.Path, err)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("writing output for file %q: %w", file.Path, err)
+			}
+			summary.Lines += lines
+			summary.Bytes += size
+			summary.addFile(file)
 			continue
 		}
 
-		formatted, err := p.formatter.Format(file.Path, file.Language, content)
+		blocks, err := p.fileBlocks(file)
 		if err != nil {
-			return fmt.Errorf("formatting file %q: %w", file.Path, err)
+			fmt.Fprintf(os.Stderr, "warning: skipping unreadable file %s: %v\n", file.Path, err)
+			continue
+		}
+
+		for _, b := range blocks {
+			formatted, err := p.formatter.Format(b.name, b.language, b.content)
+			if err != nil {
+				return fmt.Errorf("formatting file %q: %w", file.Path, err)
+			}
+
+			if _, err := w.Write(formatted); err != nil {
+				return fmt.Errorf("writing output for file %q: %w", file.Path, err)
+			}
+
+			summary.Lines += countLines(b.content)
+			summary.Bytes += int64(len(b.content))
 		}
 
-		if _, err := p.output.Write(formatted); err != nil {
-			return fmt.Errorf("writing output for file %q: %w", file.Path, err)
+		summary.addFile(file)
+	}
+
+	if isDocument {
+		end, err := docFormatter.End(summary)
+		if err != nil {
+			return fmt.Errorf("formatting document footer: %w", err)
+		}
+		if _, err := w.Write(end); err != nil {
+			return fmt.Errorf("writing document footer: %w", err)
 		}
 	}
 	return nil
 }
 
+// newSummary starts the summary of a document, listing the planned files
+// that are not packed in it.
+func (p *Packer) newSummary(unpacked []PlannedFile) Summary {
+	summary := Summary{Header: p.opts.Header}
+	for _, file := range unpacked {
+		if file.Deleted {
+			summary.Deleted = append(summary.Deleted, file)
+		} else {
+			summary.Omitted = append(summary.Omitted, file)
+		}
+	}
+	return summary
+}
+
+// addFile counts a packed file in the summary.
+func (s *Summary) addFile(file PlannedFile) {
+	s.Files++
+	if file.Deleted {
+		s.Deleted = append(s.Deleted, file)
+	}
+	if file.Truncated {
+		s.Truncated = append(s.Truncated, file)
+	}
+	if file.RenamedFrom != "" {
+		s.Renamed = append(s.Renamed, file)
+	}
+}
+
+// block is a unit of formatted output: the content of a file, or its diff.
+type block struct {
+	name     string
+	language string
+	content  []byte
+}
+
+// fileBlocks returns the blocks packed for a planned file: its content, or
+// with a Differ, its diff preceded by its content if requested. A file
+// without differences has no diff block, and a deleted file no content.
+func (p *Packer) fileBlocks(file PlannedFile) ([]block, error) {
+	var blocks []block
+	if !file.Deleted && (p.opts.Diff == nil || p.opts.DiffWithContent) {
+		content, err := p.readContent(file)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block{name: file.DisplayName(), language: file.Language, content: content})
+	}
+
+	if p.opts.Diff != nil {
+		diff, err := p.readDiff(file)
+		if err != nil {
+			return nil, err
+		}
+		if len(diff) > 0 {
+			blocks = append(blocks, block{name: file.DisplayName() + " (diff)", language: "diff", content: diff})
+		}
+	}
+	return blocks, nil
+}
+
+// readDiff returns the unified diff of a whole planned file, passed through
+// the transforms as a file in the "diff" language.
+func (p *Packer) readDiff(file PlannedFile) ([]byte, error) {
+	path, err := filepath.Abs(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	oldPath := path
+	if file.RenamedFrom != "" {
+		if oldPath, err = filepath.Abs(file.RenamedFrom); err != nil {
+			return nil, err
+		}
+	}
+
+	diff, err := p.opts.Diff.Diff(path, oldPath)
+	if err != nil {
+		return nil, err
+	}
+
+	diffFile := file
+	diffFile.Language, diffFile.Range = "diff", nil
+	for _, transform := range p.opts.Transforms {
+		transformed, err := transform.Apply(diffFile, diff)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not transform the diff of %s: %v\n", file.Path, err)
+			continue
+		}
+		diff = transformed
+	}
+	return diff, nil
+}
+
+// readContent reads a planned file and returns the content as it will be
+// packed: restricted to its line range, transformed, restricted to its
+// piece, and numbered if requested.
+func (p *Packer) readContent(file PlannedFile) ([]byte, error) {
+	content, err := p.readTransformed(file)
+	if err != nil {
+		return nil, err
+	}
+	return p.selectPiece(file, content), nil
+}
+
+// readTransformed reads a planned file, restricts it to its line range and
+// passes it through the transforms. A failing transform is reported and skipped.
+func (p *Packer) readTransformed(file PlannedFile) ([]byte, error) {
+	content, err := readSelection(p.source(), file)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, transform := range p.opts.Transforms {
+		transformed, err := transform.Apply(file, content)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not transform %s: %v\n", file.Path, err)
+			continue
+		}
+		content = transformed
+	}
+	return content, nil
+}
+
+// selectPiece restricts the transformed content of a planned file to its
+// piece, if any, and numbers its lines if requested.
+func (p *Packer) selectPiece(file PlannedFile, content []byte) []byte {
+	first := file.firstLine()
+	if file.Piece != nil {
+		content = file.Piece.Slice(content)
+		first += file.Piece.Start - 1
+	}
+	if p.opts.LineNumbers {
+		content = numberLines(content, first)
+	}
+	return content
+}
+
+// readSelection reads a planned file from src and restricts it to its line range.
+func readSelection(src source.Source, file PlannedFile) ([]byte, error) {
+	content, err := source.ReadFile(src, file.Path)
+	if err != nil {
+		return nil, err
+	}
+	if file.Range == nil {
+		return content, nil
+	}
+	return file.Range.Slice(content), nil
+}
+
+// CountTokens reads every planned file and records its estimated token
+// count in the Tokens field. Unreadable files are reported and left at zero.
+func (p *Packer) CountTokens(plan []PlannedFile) {
+	counter := p.tokenizer()
+	for i := range plan {
+		if plan[i].Deleted && !p.packsDeleted() {
+			continue
+		}
+		blocks, err := p.fileBlocks(plan[i])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not count tokens of %s: %v\n", plan[i].Path, err)
+			continue
+		}
+		plan[i].Tokens = 0
+		for _, b := range blocks {
+			plan[i].Tokens += counter.Count(b.content)
+		}
+	}
+}
+
 // processPattern finds all files matching a pattern and adds them to the plan.
-func (p *Packer) processPattern(pattern string, uniqueFiles map[string]string, isFromInclude bool) error {
-	processedPattern, err := preparePattern(pattern)
+// A pattern naming a single file may carry a selector, as in "file.go:40-120"
+// or "file.go#Func", to restrict the plan to part of the file.
+func (p *Packer) processPattern(pattern string, uniqueFiles map[string]candidate, isFromInclude bool) error {
+	src := p.source()
+	processedPattern, err := preparePattern(src, pattern)
+	if err != nil {
+		return err
+	}
+
+	path, sel, err := splitSelector(src, processedPattern)
 	if err != nil {
 		return err
 	}
+	if sel != nil {
+		lines, err := p.resolveSelector(path, sel)
+		if err != nil {
+			return err
+		}
+		c := candidate{explicit: true, lines: lines, symbol: sel.symbol}
+		p.addFileToPlan(path, path, uniqueFiles, isFromInclude, c)
+		return nil
+	}
 
-	matches, err := doublestar.FilepathGlob(processedPattern)
+	// A pattern naming a single file, rather than a glob or a directory, is explicit.
+	isExplicit := !strings.HasSuffix(processedPattern, "**") && !hasGlobMeta(processedPattern)
+
+	matches, err := p.walkPattern(processedPattern, isFromInclude)
 	if err != nil {
 		return fmt.Errorf("glob pattern %q failed: %w", processedPattern, err)
 	}
 
 	for _, match := range matches {
-		p.addFileToPlan(match, processedPattern, uniqueFiles, isFromInclude)
+		p.addFileToPlan(match, processedPattern, uniqueFiles, isFromInclude, candidate{explicit: isExplicit})
 	}
 	return nil
 }
 
 // addFileToPlan validates a single file path and, if it passes all checks,
-// adds it to the map of unique files for processing.
-func (p *Packer) addFileToPlan(path, pattern string, uniqueFiles map[string]string, isFromInclude bool) {
-	info, err := os.Stat(path)
+// adds it to the map of unique files for processing. The candidate c carries
+// the properties of the pattern that matched the file.
+func (p *Packer) addFileToPlan(path, pattern string, uniqueFiles map[string]candidate, isFromInclude bool, c candidate) {
+	info, err := p.source().Stat(path)
 	if err != nil || info.IsDir() {
 		return
 	}
@@ -144,8 +641,13 @@ func (p *Packer) addFileToPlan(path, pattern string, uniqueFiles map[string]stri
 	if err != nil {
 		return
 	}
+	c.path, c.absPath = path, absPath
 
-	if _, exists := uniqueFiles[absPath]; exists {
+	if existing, exists := uniqueFiles[c.key()]; exists {
+		if c.explicit && !existing.explicit {
+			existing.explicit = true
+			uniqueFiles[c.key()] = existing
+		}
 		return
 	}
 
@@ -153,16 +655,21 @@ func (p *Packer) addFileToPlan(path, pattern string, uniqueFiles map[string]stri
 		return
 	}
 
-	if !isFromInclude && p.filter.IsGitIgnored(absPath, false) {
+	if !isFromInclude && p.filter.IsIgnored(absPath, false) {
 		return
 	}
 
-	uniqueFiles[absPath] = path
+	uniqueFiles[c.key()] = c
+}
+
+// hasGlobMeta reports whether a pattern contains glob metacharacters.
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[{")
 }
 
-// preparePattern expands a tilde prefix and converts directory paths into
-// recursive glob patterns (e.g., "mydir/" becomes "mydir/**").
-func preparePattern(pattern string) (string, error) {
+// preparePattern expands a tilde prefix and converts directory paths of src
+// into recursive glob patterns (e.g., "mydir/" becomes "mydir/**").
+func preparePattern(src source.Source, pattern string) (string, error) {
 	expanded, err := expandTilde(pattern)
 	if err != nil {
 		return "", err
@@ -172,7 +679,7 @@ func preparePattern(pattern string) (string, error) {
 		return filepath.Join(expanded, "**"), nil
 	}
 
-	info, err := os.Stat(expanded)
+	info, err := src.Stat(expanded)
 	if err == nil && info.IsDir() {
 		return filepath.Join(expanded, "**"), nil
 	}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/jbwfu/syntex/internal/source"
 )
 
 func TestPreparePattern(t *testing.T) {
@@ -60,7 +62,7 @@ func TestPreparePattern(t *testing.T) {
 	for _, tc := range testCases {
 		// t.Run creates a sub-test, which gives clearer output on failure.
 		t.Run(tc.name, func(t *testing.T) {
-			actual, err := preparePattern(tc.input)
+			actual, err := preparePattern(source.NewOS(), tc.input)
 
 			if (err != nil) != tc.wantErr {
 				t.Errorf("preparePattern() error = %v, wantErr %v", err, tc.wantErr)
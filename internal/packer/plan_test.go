@@ -1,15 +1,21 @@
 package packer
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/jbwfu/syntex/internal/filter"
 	"github.com/jbwfu/syntex/internal/language"
+	"github.com/jbwfu/syntex/internal/source"
 )
 
 // setupTestEnvironment creates a temporary directory structure with a git repo
@@ -166,7 +172,7 @@ func TestPacker_Plan(t *testing.T) {
 			}
 			filterManager, _ := filter.NewManager(opts)
 			detector := language.NewDetector()
-			packer := NewPacker(nil, nil, filterManager, detector)
+			packer := NewPacker(nil, nil, filterManager, detector, Options{})
 
 			plan, err := packer.Plan(tc.targets)
 			if err != nil {
@@ -187,3 +193,109 @@ func TestPacker_Plan(t *testing.T) {
 		})
 	}
 }
+
+func TestPacker_PlanRevision(t *testing.T) {
+	root, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	os.WriteFile("main.go", []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join("src", "app.go"), []byte("package src\n"), 0644)
+	os.WriteFile(filepath.Join("src", "logo.bin"), []byte{0x89, 'P', 'N', 'G', 0, 0, 0, 0}, 0644)
+
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	for _, name := range []string{"main.go", "src/app.go", "src/logo.bin", "README.md"} {
+		if _, err := wt.Add(name); err != nil {
+			t.Fatalf("failed to stage %s: %v", name, err)
+		}
+	}
+	_, err = wt.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	// The working tree diverges from the revision after the commit.
+	os.WriteFile("main.go", []byte("package changed\n"), 0644)
+	os.Remove("README.md")
+	os.WriteFile(filepath.Join("src", "new.go"), []byte("package src\n"), 0644)
+
+	src, err := source.NewGit(root, "HEAD")
+	if err != nil {
+		t.Fatalf("failed to open revision: %v", err)
+	}
+	filterManager, err := filter.NewManager(filter.Options{DisableGitignore: true, ExcludePatterns: []string{"src/app.go"}})
+	if err != nil {
+		t.Fatalf("failed to create filter manager: %v", err)
+	}
+
+	var out bytes.Buffer
+	p := NewPacker(NewMarkdownFormatter(), &out, filterManager, language.NewSourceDetector(src), Options{Source: src})
+	plan, err := p.Plan([]string{"."})
+	if err != nil {
+		t.Fatalf("Plan() returned an unexpected error: %v", err)
+	}
+
+	var paths []string
+	for _, file := range plan {
+		paths = append(paths, file.Path)
+	}
+	want := []string{"README.md", "main.go"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Plan() mismatch:\ngot:  %v\nwant: %v", paths, want)
+	}
+
+	if err := p.Execute(plan); err != nil {
+		t.Fatalf("Execute() returned an unexpected error: %v", err)
+	}
+	if got := out.String(); !strings.Contains(got, "package main\n") || strings.Contains(got, "package changed") {
+		t.Errorf("Execute() did not read the revision:\n%s", got)
+	}
+}
+
+func TestPacker_PlanJobs(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Files without a known extension are analyzed by reading their content.
+	os.MkdirAll("data", 0755)
+	for i := range 40 {
+		content := []byte("#!/bin/sh\necho hello\n")
+		if i%4 == 0 {
+			content = []byte{0x7f, 'E', 'L', 'F', 0, 0, 0, 0}
+		}
+		os.WriteFile(filepath.Join("data", fmt.Sprintf("file%02d", i)), content, 0644)
+	}
+	targets := []string{"data", "src", "main.go", "main.go:1-1"}
+
+	plan := func(jobs int) []PlannedFile {
+		t.Helper()
+		filterManager, err := filter.NewManager(filter.Options{})
+		if err != nil {
+			t.Fatalf("failed to create filter manager: %v", err)
+		}
+		p := NewPacker(nil, nil, filterManager, language.NewDetector(), Options{Jobs: jobs})
+		plan, err := p.Plan(targets)
+		if err != nil {
+			t.Fatalf("Plan() returned an unexpected error: %v", err)
+		}
+		return plan
+	}
+
+	want := plan(1)
+	if len(want) != 30+1+2 {
+		t.Fatalf("Plan() returned %d files, want 33 without the binary files", len(want))
+	}
+	for range 5 {
+		if got := plan(8); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Plan() with 8 jobs differs from a single job:\ngot:  %v\nwant: %v", got, want)
+		}
+	}
+}
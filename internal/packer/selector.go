@@ -0,0 +1,79 @@
+package packer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jbwfu/syntex/internal/gocode"
+	"github.com/jbwfu/syntex/internal/source"
+)
+
+// lineSelectorPattern matches a ":start-end" or ":line" suffix of a target.
+var lineSelectorPattern = regexp.MustCompile(`^(.+):(\d+)(?:-(\d+))?$`)
+
+// selector restricts a target file to a line range or a named Go symbol.
+type selector struct {
+	lines  *LineRange
+	symbol string
+}
+
+// splitSelector separates a "path:start-end", "path:line" or "path#symbol"
+// target into the file path and its selector. A target that exists in src
+// as written, or whose path part is not an existing file, has no selector.
+func splitSelector(src source.Source, target string) (string, *selector, error) {
+	if _, err := src.Stat(target); err == nil {
+		return target, nil, nil
+	}
+
+	if m := lineSelectorPattern.FindStringSubmatch(target); m != nil && isRegularFile(src, m[1]) {
+		start, _ := strconv.Atoi(m[2])
+		end := start
+		if m[3] != "" {
+			end, _ = strconv.Atoi(m[3])
+		}
+		if start < 1 || end < start {
+			return "", nil, fmt.Errorf("invalid line range %s-%s in target %q", m[2], m[3], target)
+		}
+		return m[1], &selector{lines: &LineRange{Start: start, End: end}}, nil
+	}
+
+	if i := strings.LastIndex(target, "#"); i > 0 && i < len(target)-1 && isRegularFile(src, target[:i]) {
+		return target[:i], &selector{symbol: target[i+1:]}, nil
+	}
+
+	return target, nil, nil
+}
+
+// resolveSelector returns the line range selected in the file at path.
+// Symbols are looked up in the Go syntax tree of the file.
+func (p *Packer) resolveSelector(path string, sel *selector) (*LineRange, error) {
+	if sel.symbol == "" {
+		return sel.lines, nil
+	}
+
+	analysis, err := p.detector.AnalyzeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if analysis.Language != "go" {
+		return nil, fmt.Errorf("symbol target %s#%s: symbols are only supported in Go files", path, sel.symbol)
+	}
+
+	content, err := source.ReadFile(p.source(), path)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := gocode.FindSymbol(content, sel.symbol)
+	if err != nil {
+		return nil, fmt.Errorf("symbol target %s#%s: %w", path, sel.symbol, err)
+	}
+	return &LineRange{Start: start, End: end}, nil
+}
+
+// isRegularFile reports whether path exists in src and is not a directory.
+func isRegularFile(src source.Source, path string) bool {
+	info, err := src.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,91 @@
+package packer
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jbwfu/syntex/internal/filter"
+	"github.com/jbwfu/syntex/internal/language"
+	"github.com/jbwfu/syntex/internal/source"
+)
+
+func TestSplitSelector(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	os.WriteFile("odd:1-2", nil, 0644)
+
+	testCases := []struct {
+		name     string
+		target   string
+		wantPath string
+		wantSel  *selector
+		wantErr  bool
+	}{
+		{name: "plain file", target: "main.go", wantPath: "main.go"},
+		{name: "glob", target: "**/*.go", wantPath: "**/*.go"},
+		{name: "line range", target: "main.go:40-120", wantPath: "main.go", wantSel: &selector{lines: &LineRange{Start: 40, End: 120}}},
+		{name: "single line", target: "src/app.go:7", wantPath: "src/app.go", wantSel: &selector{lines: &LineRange{Start: 7, End: 7}}},
+		{name: "symbol", target: "main.go#run", wantPath: "main.go", wantSel: &selector{symbol: "run"}},
+		{name: "method symbol", target: "main.go#Packer.Plan", wantPath: "main.go", wantSel: &selector{symbol: "Packer.Plan"}},
+		{name: "existing file name containing a colon", target: "odd:1-2", wantPath: "odd:1-2"},
+		{name: "range on a missing file is not a selector", target: "missing.go:1-2", wantPath: "missing.go:1-2"},
+		{name: "range on a directory is not a selector", target: "src:1-2", wantPath: "src:1-2"},
+		{name: "reversed range", target: "main.go:9-3", wantErr: true},
+		{name: "line zero", target: "main.go:0-3", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path, sel, err := splitSelector(source.NewOS(), tc.target)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("splitSelector(%q) error = %v, wantErr %v", tc.target, err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if path != tc.wantPath {
+				t.Errorf("splitSelector(%q) path = %q, want %q", tc.target, path, tc.wantPath)
+			}
+			if !reflect.DeepEqual(sel, tc.wantSel) {
+				t.Errorf("splitSelector(%q) selector = %+v, want %+v", tc.target, sel, tc.wantSel)
+			}
+		})
+	}
+}
+
+func TestPacker_PlanSelectors(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	os.WriteFile("main.go", []byte("package main\n\n// run does the work.\nfunc run() {\n}\n\nfunc main() { run() }\n"), 0644)
+
+	filterManager, _ := filter.NewManager(filter.Options{})
+	p := NewPacker(nil, nil, filterManager, language.NewDetector(), Options{})
+
+	plan, err := p.Plan([]string{"main.go#run", "main.go:1-1", "main.go", "README.md#run"})
+	if err != nil {
+		t.Fatalf("Plan() returned an unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, file := range plan {
+		got = append(got, file.DisplayName())
+		if !file.Explicit {
+			t.Errorf("%s: expected selector targets to be explicit", file.DisplayName())
+		}
+	}
+	want := []string{"main.go", "main.go#run:3-5", "main.go:1-1"}
+	if !reflect.DeepEqual(sortedCopy(got), sortedCopy(want)) {
+		t.Errorf("Plan() mismatch:\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+// sortedCopy returns a sorted copy of s.
+func sortedCopy(s []string) []string {
+	c := append([]string(nil), s...)
+	sort.Strings(c)
+	return c
+}
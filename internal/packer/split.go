@@ -0,0 +1,352 @@
+package packer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+)
+
+// SplitLimit bounds the size of each part of a split output. A zero field
+// means that dimension is unlimited.
+type SplitLimit struct {
+	Bytes  int
+	Tokens int
+}
+
+// splitPieceSlack is reserved for the longer display name of a file piece,
+// such as "path:1234-5678", when splitting an oversized file.
+const splitPieceSlack = 32
+
+// PartOpener creates the writer for one part of a split output. It is
+// called once per part, in order, after the number of parts is known.
+type PartOpener func(part, parts int) (io.WriteCloser, error)
+
+// ExecuteSplit is like Execute, but distributes the files over as many parts
+// as needed to keep each within limit, and returns the number of parts.
+// Files are kept whole unless a single file exceeds the limit on its own,
+// in which case its packed content is split at line boundaries. The
+// preamble of each part states its number, and omitted files are reported
+// at the end of the last one.
+func (p *Packer) ExecuteSplit(plan []PlannedFile, limit SplitLimit, open PartOpener) (int, error) {
+	if p.opts.Diff != nil {
+		return 0, fmt.Errorf("splitting packed diffs is not supported")
+	}
+
+	included, omitted := p.partitionPlan(plan)
+	parts, err := p.assignParts(included, omitted, limit.normalize())
+	if err != nil {
+		return 0, err
+	}
+
+	for i, files := range parts {
+		doc := p.newDocument(files)
+		doc.Part, doc.Parts = i+1, len(parts)
+
+		w, err := open(doc.Part, doc.Parts)
+		if err != nil {
+			return 0, err
+		}
+
+		var partOmitted []PlannedFile
+		if doc.Part == doc.Parts {
+			partOmitted = omitted
+		}
+
+		err = p.writeDocument(w, doc, partOmitted)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return 0, fmt.Errorf("writing part %d: %w", doc.Part, err)
+		}
+	}
+	return len(parts), nil
+}
+
+// errSplitLimitTooSmall reports a split limit that leaves no room for files
+// next to the preamble and trailer of a part.
+var errSplitLimitTooSmall = errors.New("split limit is too small for the document preamble and trailer")
+
+// assignParts greedily packs files into parts whose formatted size,
+// including the preamble and trailer of the part, stays within limit,
+// splitting any file that does not fit into a part by itself. The trailer
+// listing the omitted files ends the last part, or a part of its own if it
+// does not fit there.
+func (p *Packer) assignParts(files, omitted []PlannedFile, limit SplitLimit) ([][]PlannedFile, error) {
+	empty, err := p.measurePartFrame(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if empty.Bytes >= limit.Bytes || empty.Tokens >= limit.Tokens {
+		return nil, errSplitLimitTooSmall
+	}
+
+	// The frame of the whole plan bounds that of any part, so only parts
+	// close to the limit need their own frame measured.
+	bound, err := p.measurePartFrame(files, omitted)
+	if err != nil {
+		return nil, err
+	}
+	fits := func(files, omitted []PlannedFile, size SplitLimit) (bool, error) {
+		if size.add(bound).within(limit) {
+			return true, nil
+		}
+		frame, err := p.measurePartFrame(files, omitted)
+		return size.add(frame).within(limit), err
+	}
+
+	parts := [][]PlannedFile{nil}
+	var used SplitLimit
+
+	add := func(file PlannedFile, size SplitLimit) error {
+		last := len(parts) - 1
+		if len(parts[last]) > 0 {
+			ok, err := fits(append(slices.Clip(parts[last]), file), nil, used.add(size))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				parts = append(parts, nil)
+				last++
+				used = SplitLimit{}
+			}
+		}
+		parts[last] = append(parts[last], file)
+		used = used.add(size)
+		return nil
+	}
+
+	for _, file := range files {
+		content, err := p.readTransformed(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping unreadable file %s: %v\n", file.Path, err)
+			continue
+		}
+
+		size, err := p.measureFile(file, p.selectPiece(file, content))
+		if err != nil {
+			return nil, err
+		}
+		frame, err := p.measurePartFrame([]PlannedFile{file}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if size.add(frame).within(limit) {
+			if err := add(file, size); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		pieces, err := p.splitFile(file, content, limit.sub(frame))
+		if err != nil {
+			return nil, err
+		}
+		for _, piece := range pieces {
+			if err := add(piece.file, piece.size); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(omitted) > 0 {
+		last := len(parts) - 1
+		ok, err := fits(parts[last], omitted, used)
+		if err != nil {
+			return nil, err
+		}
+		if !ok && len(parts[last]) > 0 {
+			parts = append(parts, nil)
+			if ok, err = fits(nil, omitted, SplitLimit{}); err != nil {
+				return nil, err
+			}
+		}
+		if !ok {
+			return nil, errSplitLimitTooSmall
+		}
+	}
+	return parts, nil
+}
+
+// filePiece is a line range of an oversized file together with its formatted size.
+type filePiece struct {
+	file PlannedFile
+	size SplitLimit
+}
+
+// splitFile cuts the transformed content of an oversized file into pieces
+// of consecutive lines that each fit within capacity once formatted. A
+// single line longer than capacity becomes a piece of its own. If the
+// content cannot be cut into several pieces, the file is returned whole.
+func (p *Packer) splitFile(file PlannedFile, content []byte, capacity SplitLimit) ([]filePiece, error) {
+	overhead, err := p.measureFile(file, nil)
+	if err != nil {
+		return nil, err
+	}
+	room := capacity.sub(overhead.add(SplitLimit{Bytes: splitPieceSlack, Tokens: splitPieceSlack}))
+
+	var lines [][]byte
+	for len(content) > 0 {
+		text := content
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			text = content[:i+1]
+		}
+		lines = append(lines, text)
+		content = content[len(text):]
+	}
+
+	// pieceContent returns lines start to end, exclusive, as they are packed.
+	first := file.firstLine()
+	pieceContent := func(start, end int) []byte {
+		content := bytes.Join(lines[start:end], nil)
+		if p.opts.LineNumbers {
+			content = numberLines(content, first+start)
+		}
+		return content
+	}
+
+	// Estimate the formatted size of every line, as escaping may make it
+	// larger than the line itself.
+	costs := make([]SplitLimit, len(lines))
+	for i := range lines {
+		size, err := p.measureFile(file, pieceContent(i, i+1))
+		if err != nil {
+			return nil, err
+		}
+		costs[i] = size.sub(overhead)
+	}
+
+	var pieces []filePiece
+	for start := 0; start < len(lines); {
+		end, used := start+1, costs[start]
+		for end < len(lines) && used.add(costs[end]).within(room) {
+			used = used.add(costs[end])
+			end++
+		}
+
+		// Measure the formatted piece, and give lines back until it fits.
+		var piece filePiece
+		for {
+			piece.file = file
+			piece.file.Piece = &LineRange{Start: start + 1, End: end}
+			piece.size, err = p.measureFile(piece.file, pieceContent(start, end))
+			if err != nil {
+				return nil, err
+			}
+			if piece.size.within(capacity) || end == start+1 {
+				break
+			}
+			end--
+		}
+		pieces = append(pieces, piece)
+		start = end
+	}
+
+	if len(pieces) < 2 {
+		size, err := p.measureFile(file, pieceContent(0, len(lines)))
+		if err != nil {
+			return nil, err
+		}
+		return []filePiece{{file: file, size: size}}, nil
+	}
+	fmt.Fprintf(os.Stderr, "warning: split %s into %d pieces to fit the part size\n", file.Path, len(pieces))
+	return pieces, nil
+}
+
+// measureFile returns the formatted size of a file.
+func (p *Packer) measureFile(file PlannedFile, content []byte) (SplitLimit, error) {
+	formatted, err := p.measuringFormatter().Format(file.DisplayName(), file.Language, content)
+	if err != nil {
+		return SplitLimit{}, fmt.Errorf("formatting file %q: %w", file.Path, err)
+	}
+	return p.measure(formatted), nil
+}
+
+// measurePartFrame returns the combined size of the preamble and trailer of
+// a part of a split output holding files, with room for any part number.
+func (p *Packer) measurePartFrame(files, omitted []PlannedFile) (SplitLimit, error) {
+	doc := p.newDocument(files)
+	doc.Part, doc.Parts = maxSummaryCount, maxSummaryCount
+	return p.measureDocumentFrame(doc, omitted)
+}
+
+// measureDocumentFrame returns the combined size of the preamble and trailer of doc.
+func (p *Packer) measureDocumentFrame(doc Document, omitted []PlannedFile) (SplitLimit, error) {
+	docFormatter, isDocument := p.measuringFormatter().(DocumentFormatter)
+	if !isDocument {
+		return SplitLimit{}, nil
+	}
+
+	begin, err := docFormatter.Begin(doc)
+	if err != nil {
+		return SplitLimit{}, fmt.Errorf("formatting document header: %w", err)
+	}
+
+	summary := p.newSummary(omitted)
+	for _, file := range doc.Files {
+		summary.addFile(file)
+	}
+	summary.Lines, summary.Bytes = maxSummaryCount, maxSummaryCount
+	end, err := docFormatter.End(summary)
+	if err != nil {
+		return SplitLimit{}, fmt.Errorf("formatting document footer: %w", err)
+	}
+
+	return p.measure(append(begin, end...)), nil
+}
+
+// statefulFormatter is implemented by formatters whose output depends on
+// the files formatted before, such as the index of an XML document.
+type statefulFormatter interface {
+	Formatter
+	// clone returns a copy of the formatter with the same state.
+	clone() Formatter
+}
+
+// measuringFormatter returns the formatter to measure output with: a copy
+// of a stateful formatter, so that measuring leaves its state untouched.
+func (p *Packer) measuringFormatter() Formatter {
+	if stateful, ok := p.formatter.(statefulFormatter); ok {
+		return stateful.clone()
+	}
+	return p.formatter
+}
+
+// maxSummaryCount is a placeholder large enough to measure the widest totals line.
+const maxSummaryCount = math.MaxInt32
+
+// measure returns the byte and token size of formatted output.
+func (p *Packer) measure(formatted []byte) SplitLimit {
+	return SplitLimit{Bytes: len(formatted), Tokens: p.tokenizer().Count(formatted)}
+}
+
+// normalize replaces the zero value of unlimited dimensions with math.MaxInt,
+// so that sizes can be compared and subtracted uniformly.
+func (l SplitLimit) normalize() SplitLimit {
+	if l.Bytes <= 0 {
+		l.Bytes = math.MaxInt
+	}
+	if l.Tokens <= 0 {
+		l.Tokens = math.MaxInt
+	}
+	return l
+}
+
+// add returns the component-wise sum of two sizes.
+func (l SplitLimit) add(o SplitLimit) SplitLimit {
+	return SplitLimit{Bytes: l.Bytes + o.Bytes, Tokens: l.Tokens + o.Tokens}
+}
+
+// sub returns the component-wise difference of two sizes.
+func (l SplitLimit) sub(o SplitLimit) SplitLimit {
+	return SplitLimit{Bytes: l.Bytes - o.Bytes, Tokens: l.Tokens - o.Tokens}
+}
+
+// within reports whether the size fits into limit in both dimensions.
+func (l SplitLimit) within(limit SplitLimit) bool {
+	return l.Bytes <= limit.Bytes && l.Tokens <= limit.Tokens
+}
@@ -0,0 +1,267 @@
+package packer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// nopWriteCloser adapts a bytes.Buffer to io.WriteCloser for collecting parts.
+type nopWriteCloser struct {
+	*bytes.Buffer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestPacker_ExecuteSplit(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, lines int) PlannedFile {
+		path := filepath.Join(dir, name)
+		var content strings.Builder
+		for i := 1; i <= lines; i++ {
+			fmt.Fprintf(&content, "%s line %03d\n", name, i)
+		}
+		if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return PlannedFile{Path: path, Language: "text"}
+	}
+
+	plan := []PlannedFile{
+		write("a.txt", 5),
+		write("b.txt", 5),
+		write("big.txt", 40),
+		write("c.txt", 5),
+	}
+
+	const limit = 400
+	var parts []*bytes.Buffer
+	p := NewPacker(NewMarkdownFormatter(), nil, nil, nil, Options{Tokenizer: byteCounter{}})
+	n, err := p.ExecuteSplit(plan, SplitLimit{Bytes: limit}, func(part, total int) (io.WriteCloser, error) {
+		if part != len(parts)+1 {
+			t.Errorf("part %d opened out of order", part)
+		}
+		buf := &bytes.Buffer{}
+		parts = append(parts, buf)
+		return nopWriteCloser{buf}, nil
+	})
+	if err != nil {
+		t.Fatalf("ExecuteSplit() returned an unexpected error: %v", err)
+	}
+	if n != len(parts) || n < 3 {
+		t.Fatalf("ExecuteSplit() returned %d parts and opened %d, want at least 3", n, len(parts))
+	}
+
+	var all strings.Builder
+	for i, part := range parts {
+		if part.Len() > limit {
+			t.Errorf("part %d has %d bytes, want at most %d", i+1, part.Len(), limit)
+		}
+		if want := fmt.Sprintf("Part %d of %d.\n\n", i+1, n); !strings.HasPrefix(part.String(), want) {
+			t.Errorf("part %d does not start with %q", i+1, want)
+		}
+		all.WriteString(part.String())
+	}
+
+	// Small files are never split, and every line of the big file appears exactly once.
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if got := strings.Count(all.String(), "- "+filepath.Join(dir, name)+"\n"); got != 1 {
+			t.Errorf("%s appears under %d whole-file headings, want 1", name, got)
+		}
+	}
+	for i := 1; i <= 40; i++ {
+		line := fmt.Sprintf("big.txt line %03d\n", i)
+		if got := strings.Count(all.String(), line); got != 1 {
+			t.Errorf("%q appears %d times, want 1", line, got)
+		}
+	}
+	if !strings.Contains(all.String(), "big.txt:1-") {
+		t.Errorf("oversized file was not split into line ranges:\n%s", all.String())
+	}
+}
+
+func TestPacker_ExecuteSplitLimitTooSmall(t *testing.T) {
+	p := NewPacker(NewXMLFormatter(), nil, nil, nil, Options{Tokenizer: byteCounter{}})
+	_, err := p.ExecuteSplit(nil, SplitLimit{Bytes: 10}, func(part, total int) (io.WriteCloser, error) {
+		t.Fatal("no part should be opened")
+		return nil, nil
+	})
+	if err == nil {
+		t.Error("ExecuteSplit() expected an error for a limit smaller than the XML root element")
+	}
+}
+
+func TestPacker_ExecuteSplitEscapedContent(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) PlannedFile {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return PlannedFile{Path: path, Language: "text"}
+	}
+
+	// JSON and XML escaping make every line of this file larger once formatted.
+	escaped := write("escaped.txt", strings.Repeat("\"<quoted>\" & \\back\\slashes\\\t\n", 60))
+	// A single line cannot be cut, so the file stays whole.
+	long := write("long.txt", strings.Repeat("x", 700)+"\n")
+
+	const limit = 600
+	for name, formatter := range map[string]Formatter{"json": NewJSONFormatter(), "xml": NewXMLFormatter()} {
+		t.Run(name, func(t *testing.T) {
+			var parts []*bytes.Buffer
+			p := NewPacker(formatter, nil, nil, nil, Options{Tokenizer: byteCounter{}})
+			_, err := p.ExecuteSplit([]PlannedFile{escaped, long}, SplitLimit{Bytes: limit}, func(part, total int) (io.WriteCloser, error) {
+				buf := &bytes.Buffer{}
+				parts = append(parts, buf)
+				return nopWriteCloser{buf}, nil
+			})
+			if err != nil {
+				t.Fatalf("ExecuteSplit() returned an unexpected error: %v", err)
+			}
+
+			var all strings.Builder
+			for i, part := range parts {
+				if part.Len() > limit && !strings.Contains(part.String(), "long.txt") {
+					t.Errorf("part %d has %d bytes, want at most %d", i+1, part.Len(), limit)
+				}
+				all.WriteString(part.String())
+			}
+			if !strings.Contains(all.String(), "escaped.txt:1-") {
+				t.Errorf("escaped file was not split into line ranges")
+			}
+			if strings.Contains(all.String(), "long.txt:") {
+				t.Errorf("a file packed whole is labelled with a line range")
+			}
+		})
+	}
+}
+
+func TestPacker_ExecuteSplitTransformedContent(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("package demo\n")
+	for i := range 60 {
+		fmt.Fprintf(&src, "\n// F%02d squares its argument.\n// It has a second comment line.\nfunc F%02d(x int) int {\n\t// The body is one line.\n\treturn x * x\n}\n", i, i)
+	}
+	path := filepath.Join(t.TempDir(), "demo.go")
+	if err := os.WriteFile(path, []byte(src.String()), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+
+	testCases := []struct {
+		name      string
+		transform Transform
+		// want is a line every function contributes exactly once.
+		want string
+		// wantComments is true if the transform keeps doc comments.
+		wantComments bool
+	}{
+		{name: "strip comments", transform: NewStripCommentsTransform(), want: "func F%02d(x int) int {\n"},
+		{name: "outline", transform: NewOutlineTransform(), want: "func F%02d(x int) int\n", wantComments: true},
+	}
+
+	const limit = 600
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var parts []*bytes.Buffer
+			p := NewPacker(NewMarkdownFormatter(), nil, nil, nil, Options{
+				Tokenizer:  byteCounter{},
+				Transforms: []Transform{tc.transform},
+			})
+			_, err := p.ExecuteSplit([]PlannedFile{{Path: path, Language: "go"}}, SplitLimit{Bytes: limit}, func(part, total int) (io.WriteCloser, error) {
+				buf := &bytes.Buffer{}
+				parts = append(parts, buf)
+				return nopWriteCloser{buf}, nil
+			})
+			if err != nil {
+				t.Fatalf("ExecuteSplit() returned an unexpected error: %v", err)
+			}
+			if len(parts) < 2 {
+				t.Fatalf("ExecuteSplit() wrote %d parts, want several", len(parts))
+			}
+
+			var all strings.Builder
+			for i, part := range parts {
+				if part.Len() > limit {
+					t.Errorf("part %d has %d bytes, want at most %d", i+1, part.Len(), limit)
+				}
+				all.WriteString(part.String())
+			}
+			for i := range 60 {
+				line := fmt.Sprintf(tc.want, i)
+				if got := strings.Count(all.String(), line); got != 1 {
+					t.Errorf("%q appears %d times, want 1", line, got)
+				}
+			}
+			if got := strings.Contains(all.String(), "//"); got != tc.wantComments {
+				t.Errorf("split output contains comments = %v, want %v:\n%s", got, tc.wantComments, all.String())
+			}
+		})
+	}
+}
+
+func TestPacker_ExecuteSplitPartFrames(t *testing.T) {
+	t.Chdir(t.TempDir())
+	var plan []PlannedFile
+	for i := range 30 {
+		name := fmt.Sprintf("file%02d.txt", i)
+		if err := os.WriteFile(name, []byte(strings.Repeat("x", 39)+"\n"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		plan = append(plan, PlannedFile{Path: name, Language: "text"})
+	}
+
+	const limit = 1200
+	var parts []*bytes.Buffer
+	p := NewPacker(NewMarkdownFormatter(), nil, nil, nil, Options{
+		Header:    &Header{Title: "demo"},
+		Tree:      true,
+		Tokenizer: byteCounter{},
+	})
+	whole, err := p.measurePartFrame(plan, nil)
+	if err != nil {
+		t.Fatalf("measurePartFrame() returned an unexpected error: %v", err)
+	}
+	_, err = p.ExecuteSplit(plan, SplitLimit{Bytes: limit}, func(part, total int) (io.WriteCloser, error) {
+		buf := &bytes.Buffer{}
+		parts = append(parts, buf)
+		return nopWriteCloser{buf}, nil
+	})
+	if err != nil {
+		t.Fatalf("ExecuteSplit() returned an unexpected error: %v", err)
+	}
+
+	// Each part reserves room for its own table of contents and tree only,
+	// so the files of the first part fill more than the whole plan's frame leaves.
+	for i, part := range parts {
+		if part.Len() > limit {
+			t.Errorf("part %d has %d bytes, want at most %d", i+1, part.Len(), limit)
+		}
+	}
+	if first := parts[0].Len(); first <= limit-whole.Bytes {
+		t.Errorf("first part has %d bytes, want more than the %d left by the frame of the whole plan", first, limit-whole.Bytes)
+	}
+}
+
+func TestPacker_MeasureKeepsFormatterState(t *testing.T) {
+	formatter := NewXMLFormatter()
+	p := NewPacker(formatter, nil, nil, nil, Options{Tokenizer: byteCounter{}})
+	file := PlannedFile{Path: "a.txt", Language: "text"}
+	for range 3 {
+		if _, err := p.measureFile(file, []byte("content\n")); err != nil {
+			t.Fatalf("measureFile() returned an unexpected error: %v", err)
+		}
+	}
+
+	formatted, err := formatter.Format(file.Path, file.Language, []byte("content\n"))
+	if err != nil {
+		t.Fatalf("Format() returned an unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(formatted, []byte(`<document index="1">`)) {
+		t.Errorf("measuring changed the document index:\n%s", formatted)
+	}
+}
@@ -0,0 +1,144 @@
+package packer
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// streamFormatter returns the formatter as a StreamFormatter if a planned
+// file can be copied to the output as it is read: its whole content is
+// packed, without line numbers or a diff, and every transform is a
+// StreamTransform.
+func (p *Packer) streamFormatter(file PlannedFile) (StreamFormatter, bool) {
+	if p.opts.LineNumbers || p.opts.Diff != nil || file.Range != nil || file.Piece != nil {
+		return nil, false
+	}
+	for _, transform := range p.opts.Transforms {
+		if _, ok := transform.(StreamTransform); !ok {
+			return nil, false
+		}
+	}
+	streamer, ok := p.formatter.(StreamFormatter)
+	return streamer, ok
+}
+
+// streamFile formats a planned file with a StreamFormatter, copying its
+// content from the source to w. A file that cannot be opened is reported as
+// errUnreadable before anything is written. It returns the number of lines
+// and bytes of the content.
+func (p *Packer) streamFile(w io.Writer, streamer StreamFormatter, file PlannedFile) (int, int64, error) {
+	first, err := p.source().Open(file.Path)
+	if err != nil {
+		return 0, 0, &unreadableError{err: err}
+	}
+
+	var counter *lineCounter
+	open := func() (io.ReadCloser, error) {
+		r := first
+		first = nil
+		if r == nil {
+			var err error
+			if r, err = p.source().Open(file.Path); err != nil {
+				return nil, err
+			}
+		}
+		var content io.Reader = r
+		for _, transform := range p.opts.Transforms {
+			content = transform.(StreamTransform).Stream(file, content)
+		}
+		// The counts of the last read describe the content written.
+		counter = &lineCounter{Reader: content, Closer: r}
+		return counter, nil
+	}
+	defer func() {
+		if first != nil {
+			first.Close()
+		}
+	}()
+
+	if err := streamer.FormatStream(w, file.DisplayName(), file.Language, open); err != nil {
+		return 0, 0, err
+	}
+	if counter == nil {
+		return 0, 0, nil
+	}
+	return counter.lines(), counter.bytes, nil
+}
+
+// unreadableError reports a file that could not be opened for streaming.
+type unreadableError struct {
+	err error
+}
+
+func (e *unreadableError) Error() string { return e.err.Error() }
+func (e *unreadableError) Unwrap() error { return e.err }
+
+// lineCounter counts the bytes and lines read through it.
+type lineCounter struct {
+	io.Reader
+	io.Closer
+	bytes    int64
+	newlines int
+	last     byte
+}
+
+func (c *lineCounter) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	for _, b := range p[:n] {
+		if b == '\n' {
+			c.newlines++
+		}
+	}
+	if n > 0 {
+		c.bytes += int64(n)
+		c.last = p[n-1]
+	}
+	return n, err
+}
+
+// lines returns the number of lines read, counted like countLines.
+func (c *lineCounter) lines() int {
+	if c.bytes > 0 && c.last != '\n' {
+		return c.newlines + 1
+	}
+	return c.newlines
+}
+
+// runeWriter passes content written to it on to write in whole UTF-8
+// sequences, holding back the bytes of a sequence split across writes
+// until the next write or Close.
+type runeWriter struct {
+	write   func(p []byte) error
+	pending []byte
+}
+
+func (w *runeWriter) Write(p []byte) (int, error) {
+	data := append(w.pending, p...)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	w.pending = bytes.Clone(data[cut:])
+	if cut > 0 {
+		if err := w.write(data[:cut]); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close passes on the bytes held back, which do not form a whole sequence.
+func (w *runeWriter) Close() error {
+	pending := w.pending
+	w.pending = nil
+	if len(pending) == 0 {
+		return nil
+	}
+	return w.write(pending)
+}
@@ -0,0 +1,118 @@
+package packer
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jbwfu/syntex/internal/comments"
+	"github.com/jbwfu/syntex/internal/gocode"
+	"github.com/jbwfu/syntex/internal/secrets"
+)
+
+// OutlineTransform reduces whole Go files to declarations and signatures
+// without function bodies. Files with a line range are left as selected.
+type OutlineTransform struct{}
+
+// NewOutlineTransform creates a new OutlineTransform.
+func NewOutlineTransform() *OutlineTransform {
+	return &OutlineTransform{}
+}
+
+// Apply returns the outline of a Go file, or content unchanged for other files.
+func (t *OutlineTransform) Apply(file PlannedFile, content []byte) ([]byte, error) {
+	if file.Language != "go" || file.Range != nil {
+		return content, nil
+	}
+	return gocode.Outline(content)
+}
+
+// StripCommentsTransform removes comments and collapses blank lines in the
+// languages known to the comments package. String literals are kept intact.
+type StripCommentsTransform struct{}
+
+// NewStripCommentsTransform creates a new StripCommentsTransform.
+func NewStripCommentsTransform() *StripCommentsTransform {
+	return &StripCommentsTransform{}
+}
+
+// Apply returns content without comments.
+func (t *StripCommentsTransform) Apply(file PlannedFile, content []byte) ([]byte, error) {
+	return comments.Strip(file.Language, content), nil
+}
+
+// RedactSecretsTransform replaces the secrets detected by the secrets package
+// with "[REDACTED:kind]" and reports each of them once.
+type RedactSecretsTransform struct {
+	report   io.Writer
+	reported map[string]bool
+}
+
+// NewRedactSecretsTransform creates a new RedactSecretsTransform that writes
+// its report to the given writer.
+func NewRedactSecretsTransform(report io.Writer) *RedactSecretsTransform {
+	return &RedactSecretsTransform{report: report, reported: make(map[string]bool)}
+}
+
+// Apply returns content with its secrets redacted.
+func (t *RedactSecretsTransform) Apply(file PlannedFile, content []byte) ([]byte, error) {
+	redacted, findings := secrets.Redact(file.Language, content)
+	for _, f := range findings {
+		t.reportFinding(file, f)
+	}
+	return redacted, nil
+}
+
+// Stream returns a reader redacting the secrets of the content read from r.
+func (t *RedactSecretsTransform) Stream(file PlannedFile, r io.Reader) io.Reader {
+	return secrets.NewReader(file.Language, r, func(f secrets.Finding) {
+		t.reportFinding(file, f)
+	})
+}
+
+// reportFinding reports a secret redacted in a file, unless it already was.
+func (t *RedactSecretsTransform) reportFinding(file PlannedFile, f secrets.Finding) {
+	line := fileLine(file, f.Line)
+	key := fmt.Sprintf("%s:%d:%s", file.Path, line, f.Kind)
+	if t.reported[key] {
+		return
+	}
+	t.reported[key] = true
+	fmt.Fprintf(t.report, "warning: redacted %s in %s:%d\n", f.Kind, file.Path, line)
+}
+
+// Secret is a secret detected in a planned file.
+type Secret struct {
+	Path string
+	Kind string
+	// Line is the line of the file on which the secret starts.
+	Line int
+}
+
+// FindSecrets scans the selected content of every file to be packed, before
+// any transform, and returns the secrets found. Unreadable files are skipped;
+// Execute reports them.
+func (p *Packer) FindSecrets(plan []PlannedFile) []Secret {
+	var found []Secret
+	for _, file := range plan {
+		if file.Omitted || file.Deleted {
+			continue
+		}
+		content, err := readSelection(p.source(), file)
+		if err != nil {
+			continue
+		}
+		for _, f := range secrets.Scan(file.Language, content) {
+			found = append(found, Secret{Path: file.Path, Kind: f.Kind, Line: fileLine(file, f.Line)})
+		}
+	}
+	return found
+}
+
+// fileLine converts a line number within the selected content of file into
+// a line number within the file.
+func fileLine(file PlannedFile, line int) int {
+	if file.Range != nil {
+		return file.Range.Start + line - 1
+	}
+	return line
+}
@@ -0,0 +1,160 @@
+package packer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jbwfu/syntex/internal/source"
+)
+
+// TreeNode is a directory or file in the project tree rendered before the
+// packed files.
+type TreeNode struct {
+	Name string
+	// File is set for leaf nodes and nil for directories.
+	File *PlannedFile
+	// Lines is the line count of File, or -1 if it could not be determined.
+	Lines    int
+	Children []*TreeNode
+}
+
+// buildTree arranges the planned files into a directory hierarchy, annotating
+// each file with its line count in src. Children are sorted by name.
+func buildTree(src source.Source, plan []PlannedFile) *TreeNode {
+	root := &TreeNode{Name: "."}
+	dirs := map[string]*TreeNode{"": root}
+
+	for i := range plan {
+		file := &plan[i]
+		components := splitTreePath(file.Path)
+
+		parent := root
+		for j, name := range components[:len(components)-1] {
+			key := strings.Join(components[:j+1], "/")
+			dir, ok := dirs[key]
+			if !ok {
+				dir = &TreeNode{Name: name}
+				dirs[key] = dir
+				parent.Children = append(parent.Children, dir)
+			}
+			parent = dir
+		}
+
+		lines, err := countFileLines(src, file.Path)
+		if err != nil {
+			lines = -1
+		}
+		parent.Children = append(parent.Children, &TreeNode{
+			Name:  components[len(components)-1],
+			File:  file,
+			Lines: lines,
+		})
+	}
+
+	sortTree(root)
+	return root
+}
+
+// splitTreePath splits a path into slash-separated components, keeping a
+// leading "/" as its own component for absolute paths.
+func splitTreePath(path string) []string {
+	slashed := filepath.ToSlash(filepath.Clean(path))
+	if strings.HasPrefix(slashed, "/") {
+		return append([]string{"/"}, strings.Split(strings.TrimPrefix(slashed, "/"), "/")...)
+	}
+	return strings.Split(slashed, "/")
+}
+
+// sortTree orders the children of every node by name.
+func sortTree(node *TreeNode) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		return node.Children[i].Name < node.Children[j].Name
+	})
+	for _, child := range node.Children {
+		sortTree(child)
+	}
+}
+
+// label returns the display text of a node, including the language and line
+// count annotation for files and a trailing slash for directories.
+func (n *TreeNode) label() string {
+	if n.File == nil {
+		return strings.TrimSuffix(n.Name, "/") + "/"
+	}
+	if n.Lines < 0 {
+		return fmt.Sprintf("%s (%s)", n.Name, n.File.Language)
+	}
+	return fmt.Sprintf("%s (%s, %d lines)", n.Name, n.File.Language, n.Lines)
+}
+
+// renderASCIITree draws the tree using box-drawing characters, similar to
+// the output of the tree(1) command.
+func renderASCIITree(root *TreeNode) []byte {
+	var out bytes.Buffer
+	out.WriteString(".\n")
+
+	var walk func(node *TreeNode, prefix string)
+	walk = func(node *TreeNode, prefix string) {
+		for i, child := range node.Children {
+			connector, indent := "├── ", "│   "
+			if i == len(node.Children)-1 {
+				connector, indent = "└── ", "    "
+			}
+			fmt.Fprintf(&out, "%s%s%s\n", prefix, connector, child.label())
+			walk(child, prefix+indent)
+		}
+	}
+	walk(root, "")
+	return out.Bytes()
+}
+
+// renderListTree draws the tree as a nested list with two spaces of
+// indentation per level, as used by Org Mode plain lists.
+func renderListTree(root *TreeNode) []byte {
+	var out bytes.Buffer
+
+	var walk func(node *TreeNode, depth int)
+	walk = func(node *TreeNode, depth int) {
+		for _, child := range node.Children {
+			fmt.Fprintf(&out, "%s- %s\n", strings.Repeat("  ", depth), child.label())
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+	return out.Bytes()
+}
+
+// countFileLines counts the lines of a file without loading it into memory.
+func countFileLines(src source.Source, path string) (int, error) {
+	f, err := src.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 32*1024)
+	lines := 0
+	endsWithNewline := true
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			endsWithNewline = buf[n-1] == '\n'
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if !endsWithNewline {
+		lines++
+	}
+	return lines, nil
+}
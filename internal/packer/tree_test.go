@@ -0,0 +1,83 @@
+package packer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jbwfu/syntex/internal/source"
+)
+
+func TestTreeRendering(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	os.WriteFile("main.go", []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join("src", "app.go"), []byte("package src"), 0644)
+	os.MkdirAll(filepath.Join("src", "util"), 0755)
+	os.WriteFile(filepath.Join("src", "util", "str.go"), []byte("package util\n"), 0644)
+
+	plan := []PlannedFile{
+		{Path: "main.go", Language: "go"},
+		{Path: "README.md", Language: "markdown"},
+		{Path: filepath.Join("src", "util", "str.go"), Language: "go"},
+		{Path: filepath.Join("src", "app.go"), Language: "go"},
+		{Path: "missing.txt", Language: "text"},
+	}
+	tree := buildTree(source.NewOS(), plan)
+
+	t.Run("ascii", func(t *testing.T) {
+		want := `.
+├── README.md (markdown, 0 lines)
+├── main.go (go, 3 lines)
+├── missing.txt (text)
+└── src/
+    ├── app.go (go, 1 lines)
+    └── util/
+        └── str.go (go, 1 lines)
+`
+		if got := string(renderASCIITree(tree)); got != want {
+			t.Errorf("renderASCIITree() mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("nested list", func(t *testing.T) {
+		want := `- README.md (markdown, 0 lines)
+- main.go (go, 3 lines)
+- missing.txt (text)
+- src/
+  - app.go (go, 1 lines)
+  - util/
+    - str.go (go, 1 lines)
+`
+		if got := string(renderListTree(tree)); got != want {
+			t.Errorf("renderListTree() mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+func TestSplitTreePath(t *testing.T) {
+	testCases := []struct {
+		path string
+		want []string
+	}{
+		{"main.go", []string{"main.go"}},
+		{"./src/app.go", []string{"src", "app.go"}},
+		{"../other/x.go", []string{"..", "other", "x.go"}},
+		{"/tmp/outside.js", []string{"/", "tmp", "outside.js"}},
+	}
+
+	for _, tc := range testCases {
+		got := splitTreePath(filepath.FromSlash(tc.path))
+		if len(got) != len(tc.want) {
+			t.Errorf("splitTreePath(%q) = %q, want %q", tc.path, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("splitTreePath(%q) = %q, want %q", tc.path, got, tc.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package packer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/jbwfu/syntex/internal/source"
+)
+
+// walkPattern returns the paths in the packer's source matching a doublestar
+// pattern, like doublestar.FilepathGlob. Instead of matching every file of
+// the tree first, it skips the subtrees of directories whose files would all
+// be filtered out: ignored directories, unless the pattern is an include
+// pattern, directories matching an exclude pattern and hidden directories
+// the pattern does not name explicitly.
+func (p *Packer) walkPattern(pattern string, isFromInclude bool) ([]string, error) {
+	src := p.source()
+	if !hasGlobMeta(pattern) {
+		if _, err := src.Stat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	slashed := filepath.ToSlash(pattern)
+	if !doublestar.ValidatePattern(slashed) {
+		return nil, doublestar.ErrBadPattern
+	}
+	base, glob := doublestar.SplitPattern(slashed)
+	base = filepath.FromSlash(base)
+
+	// Without "**", a pattern cannot match deeper than its number of components.
+	maxDepth := -1
+	if !strings.Contains(glob, "**") {
+		maxDepth = strings.Count(glob, "/") + 1
+	}
+
+	// Symbolic links to directories are followed, if the source resolves
+	// them, unless they lead back to a directory being walked. Directories
+	// are tracked by their real path, derived from the base's and resolved
+	// again only through links.
+	realBase, err := realPath(src, base)
+	if err != nil {
+		realBase, _ = filepath.Abs(base)
+	}
+	walking := make(map[string]bool)
+
+	var matches []string
+	var walk func(dir, real, rel string, depth int) error
+	walk = func(dir, real, rel string, depth int) error {
+		entries, err := src.ReadDir(dir)
+		if err != nil {
+			if depth == 0 {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "warning: skipping unreadable directory %s: %v\n", dir, err)
+			return nil
+		}
+		walking[real] = true
+		defer delete(walking, real)
+
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			relPath := entry.Name()
+			if rel != "" {
+				relPath = rel + "/" + entry.Name()
+			}
+
+			if match, _ := doublestar.Match(glob, relPath); match {
+				matches = append(matches, path)
+			}
+
+			if depth+1 == maxDepth {
+				continue
+			}
+			childReal, isDir := filepath.Join(real, entry.Name()), entry.IsDir()
+			if entry.Type()&fs.ModeSymlink != 0 {
+				childReal, isDir = resolveDirLink(src, path)
+			}
+			if !isDir || walking[childReal] || p.skipDir(path, pattern, isFromInclude) {
+				continue
+			}
+			if err := walk(path, childReal, relPath, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk(base, realBase, "", 0); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// realPath returns path with its symbolic links resolved by src, or just
+// made absolute if src does not resolve links.
+func realPath(src source.Source, path string) (string, error) {
+	if resolver, ok := src.(source.LinkResolver); ok {
+		return resolver.RealPath(path)
+	}
+	return filepath.Abs(path)
+}
+
+// resolveDirLink returns the real path of the target of a symbolic link,
+// and whether it is a directory to follow. Links are only followed in
+// sources that resolve them.
+func resolveDirLink(src source.Source, path string) (string, bool) {
+	resolver, ok := src.(source.LinkResolver)
+	if !ok {
+		return "", false
+	}
+	info, err := src.Stat(path)
+	if err != nil || !info.IsDir() {
+		return "", false
+	}
+	real, err := resolver.RealPath(path)
+	if err != nil {
+		return "", false
+	}
+	return real, true
+}
+
+// skipDir reports whether no file below the directory at path can pass the
+// filters applied by addFileToPlan for a given pattern.
+func (p *Packer) skipDir(path, pattern string, isFromInclude bool) bool {
+	if p.filter.IsDotfileIgnored(path, pattern) {
+		return true
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if p.filter.IsGloballyExcluded(absPath) {
+		return true
+	}
+	return !isFromInclude && p.filter.IsIgnored(absPath, true)
+}
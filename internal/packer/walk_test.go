@@ -0,0 +1,184 @@
+package packer
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jbwfu/syntex/internal/filter"
+	"github.com/jbwfu/syntex/internal/language"
+	"github.com/jbwfu/syntex/internal/source"
+)
+
+// recordingSource is a Source on the local filesystem that records the
+// directories read.
+type recordingSource struct {
+	*source.OS
+	dirs []string
+}
+
+func (s *recordingSource) ReadDir(name string) ([]fs.DirEntry, error) {
+	s.dirs = append(s.dirs, filepath.ToSlash(name))
+	return s.OS.ReadDir(name)
+}
+
+func TestPacker_WalkPrunesDirectories(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	os.MkdirAll(filepath.Join("build", "out"), 0755)
+	os.WriteFile(filepath.Join("build", "out", "gen.go"), nil, 0644)
+	os.MkdirAll(filepath.Join("src", "deep", "er"), 0755)
+	os.WriteFile(filepath.Join("src", "deep", "er", "x.go"), nil, 0644)
+
+	testCases := []struct {
+		name            string
+		targets         []string
+		includePatterns []string
+		excludePatterns []string
+		wantPlan        []string
+		wantDirs        []string
+	}{
+		{
+			name:            "ignored, excluded and hidden directories are not read",
+			targets:         []string{"."},
+			excludePatterns: []string{"build"},
+			wantPlan:        []string{"README.md", "main.go", "src/app.go", "src/deep/er/x.go"},
+			wantDirs:        []string{".", "src", "src/deep", "src/deep/er"},
+		},
+		{
+			name:     "explicit hidden directory is read",
+			targets:  []string{".test/**"},
+			wantPlan: []string{".test/kkk/oo/ll.go"},
+			wantDirs: []string{".test", ".test/kkk", ".test/kkk/oo"},
+		},
+		{
+			name:            "include patterns read ignored directories",
+			includePatterns: []string{"vendor/**"},
+			wantPlan:        []string{"vendor/lib/lib.go"},
+			wantDirs:        []string{"vendor", "vendor/lib"},
+		},
+		{
+			name:     "patterns without globstar are not read deeper than they match",
+			targets:  []string{"src/*.go"},
+			wantPlan: []string{"src/app.go"},
+			wantDirs: []string{"src"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filterManager, err := filter.NewManager(filter.Options{
+				IncludePatterns: tc.includePatterns,
+				ExcludePatterns: tc.excludePatterns,
+			})
+			if err != nil {
+				t.Fatalf("failed to create filter manager: %v", err)
+			}
+			src := &recordingSource{OS: source.NewOS()}
+			p := NewPacker(nil, nil, filterManager, language.NewDetector(), Options{Source: src})
+
+			plan, err := p.Plan(tc.targets)
+			if err != nil {
+				t.Fatalf("Plan() returned an unexpected error: %v", err)
+			}
+
+			var paths []string
+			for _, file := range plan {
+				paths = append(paths, filepath.ToSlash(file.Path))
+			}
+			if !reflect.DeepEqual(paths, tc.wantPlan) {
+				t.Errorf("Plan() mismatch:\ngot:  %v\nwant: %v", paths, tc.wantPlan)
+			}
+
+			sort.Strings(src.dirs)
+			if !reflect.DeepEqual(src.dirs, tc.wantDirs) {
+				t.Errorf("directories read mismatch:\ngot:  %v\nwant: %v", src.dirs, tc.wantDirs)
+			}
+		})
+	}
+}
+
+// failingSource is a Source on the local filesystem that cannot read one directory.
+type failingSource struct {
+	*source.OS
+	dir string
+}
+
+func (s *failingSource) ReadDir(name string) ([]fs.DirEntry, error) {
+	if filepath.ToSlash(name) == s.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("permission denied")}
+	}
+	return s.OS.ReadDir(name)
+}
+
+// unresolvedSource is a Source on the local filesystem that does not
+// resolve symbolic links, like the tree of a git revision.
+type unresolvedSource struct {
+	os *source.OS
+}
+
+func (s unresolvedSource) Open(name string) (io.ReadCloser, error)    { return s.os.Open(name) }
+func (s unresolvedSource) Stat(name string) (fs.FileInfo, error)      { return s.os.Stat(name) }
+func (s unresolvedSource) ReadDir(name string) ([]fs.DirEntry, error) { return s.os.ReadDir(name) }
+
+func TestPacker_WalkSymlinksAndErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.MkdirAll(filepath.Join("real", "sub"), 0755)
+	os.WriteFile(filepath.Join("real", "a.txt"), nil, 0644)
+	os.WriteFile(filepath.Join("real", "sub", "b.txt"), nil, 0644)
+	if err := os.Symlink("real", "linked"); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	// A link back to an ancestor must not be walked forever.
+	os.Symlink("..", filepath.Join("real", "sub", "up"))
+
+	testCases := []struct {
+		name     string
+		src      source.Source
+		wantPlan []string
+	}{
+		{
+			name:     "symlinked directories are followed once",
+			src:      source.NewOS(),
+			wantPlan: []string{"linked/a.txt", "linked/sub/b.txt", "real/a.txt", "real/sub/b.txt"},
+		},
+		{
+			name:     "unreadable directories are skipped",
+			src:      &failingSource{OS: source.NewOS(), dir: "real/sub"},
+			wantPlan: []string{"linked/a.txt", "linked/sub/b.txt", "real/a.txt"},
+		},
+		{
+			name:     "symlinks are not followed in sources that do not resolve them",
+			src:      unresolvedSource{os: source.NewOS()},
+			wantPlan: []string{"real/a.txt", "real/sub/b.txt"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filterManager, err := filter.NewManager(filter.Options{})
+			if err != nil {
+				t.Fatalf("failed to create filter manager: %v", err)
+			}
+			p := NewPacker(nil, nil, filterManager, language.NewDetector(), Options{Source: tc.src})
+
+			plan, err := p.Plan([]string{"**/*.txt"})
+			if err != nil {
+				t.Fatalf("Plan() returned an unexpected error: %v", err)
+			}
+			var paths []string
+			for _, file := range plan {
+				paths = append(paths, filepath.ToSlash(file.Path))
+			}
+			if !reflect.DeepEqual(paths, tc.wantPlan) {
+				t.Errorf("Plan() mismatch:\ngot:  %v\nwant: %v", paths, tc.wantPlan)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package packer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	cdataStart = "<![CDATA["
+	cdataEnd   = "]]>"
+)
+
+// XMLFormatter implements the DocumentFormatter interface using the
+// <documents><document index="N">...</document></documents> structure
+// recommended for long-context prompts.
+type XMLFormatter struct {
+	index int
+}
+
+// NewXMLFormatter creates a new XMLFormatter.
+func NewXMLFormatter() *XMLFormatter {
+	return &XMLFormatter{}
+}
+
+// clone returns a copy of the formatter with the same document index.
+func (f *XMLFormatter) clone() Formatter {
+	c := *f
+	return &c
+}
+
+// Begin opens the <documents> root element and resets the document index.
+// The part number of a split document precedes the root as a comment.
+func (f *XMLFormatter) Begin(doc Document) ([]byte, error) {
+	f.index = 0
+	if part := describePart(doc); part != "" {
+		return fmt.Appendf(nil, "<!-- %s -->\n<documents>\n", part), nil
+	}
+	return []byte("<documents>\n"), nil
+}
+
+// End lists the sources left out by the token budget in an
+// <omitted_documents> element, those renamed or deleted in the git changes
+// in <renamed_documents> and <deleted_documents>, and closes the
+// <documents> root element.
+func (f *XMLFormatter) End(summary Summary) ([]byte, error) {
+	var out bytes.Buffer
+
+	if err := writeXMLSources(&out, "omitted_documents", summary.Omitted); err != nil {
+		return nil, err
+	}
+
+	if len(summary.Renamed) > 0 {
+		out.WriteString("<renamed_documents>\n")
+		for _, file := range summary.Renamed {
+			out.WriteString("<document><source>")
+			if err := xml.EscapeText(&out, []byte(file.Path)); err != nil {
+				return nil, err
+			}
+			out.WriteString("</source><previous_source>")
+			if err := xml.EscapeText(&out, []byte(file.RenamedFrom)); err != nil {
+				return nil, err
+			}
+			out.WriteString("</previous_source></document>\n")
+		}
+		out.WriteString("</renamed_documents>\n")
+	}
+
+	if err := writeXMLSources(&out, "deleted_documents", summary.Deleted); err != nil {
+		return nil, err
+	}
+
+	out.WriteString("</documents>\n")
+	return out.Bytes(), nil
+}
+
+// writeXMLSources writes the paths of files as <source> elements inside an
+// element with the given name. Nothing is written if files is empty.
+func writeXMLSources(out *bytes.Buffer, name string, files []PlannedFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+	fmt.Fprintf(out, "<%s>\n", name)
+	for _, file := range files {
+		out.WriteString("<source>")
+		if err := xml.EscapeText(out, []byte(file.Path)); err != nil {
+			return err
+		}
+		out.WriteString("</source>\n")
+	}
+	fmt.Fprintf(out, "</%s>\n", name)
+	return nil
+}
+
+// Format takes file details and content, and returns it as a numbered
+// <document> element. The language is not part of this structure and is ignored.
+func (f *XMLFormatter) Format(filename, language string, content []byte) ([]byte, error) {
+	var out bytes.Buffer
+	f.index++
+
+	if err := writeXMLDocumentStart(&out, f.index, filename); err != nil {
+		return nil, err
+	}
+
+	if _, err := out.Write(escapeXMLContent(content)); err != nil {
+		return nil, err
+	}
+
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		if err := out.WriteByte('\n'); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := out.WriteString(xmlDocumentEnd); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// FormatStream writes the same <document> element as Format, reading the
+// content twice: once to find out whether it needs a CDATA section and once
+// to copy it to w.
+func (f *XMLFormatter) FormatStream(w io.Writer, filename, language string, open func() (io.ReadCloser, error)) error {
+	var scan xmlMarkupScanner
+	if err := copyFrom(&scan, open); err != nil {
+		return err
+	}
+
+	f.index++
+	if err := writeXMLDocumentStart(w, f.index, filename); err != nil {
+		return err
+	}
+
+	var content io.Writer = w
+	if scan.markup {
+		if _, err := io.WriteString(w, cdataStart); err != nil {
+			return err
+		}
+		content = &cdataWriter{w: w}
+	}
+	chars := &runeWriter{write: func(p []byte) error {
+		_, err := content.Write(sanitizeXMLChars(p))
+		return err
+	}}
+	if err := copyFrom(chars, open); err != nil {
+		return err
+	}
+	if err := chars.Close(); err != nil {
+		return err
+	}
+
+	tail := xmlDocumentEnd
+	if scan.size > 0 && scan.last != '\n' {
+		tail = "\n" + tail
+	}
+	if scan.markup {
+		tail = cdataEnd + tail
+	}
+	_, err := io.WriteString(w, tail)
+	return err
+}
+
+// xmlDocumentEnd closes the <document> element opened by writeXMLDocumentStart.
+const xmlDocumentEnd = "</document_content>\n</document>\n"
+
+// writeXMLDocumentStart opens a <document> element with the given index and
+// source, up to its content.
+func writeXMLDocumentStart(w io.Writer, index int, filename string) error {
+	if _, err := fmt.Fprintf(w, "<document index=\"%d\">\n<source>", index); err != nil {
+		return err
+	}
+	if err := xml.EscapeText(w, []byte(filename)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "</source>\n<document_content>\n")
+	return err
+}
+
+// xmlMarkupScanner records whether the content written to it needs a CDATA
+// section, as decided by escapeXMLContent, and its size and last byte.
+type xmlMarkupScanner struct {
+	markup   bool
+	brackets int
+	size     int
+	last     byte
+}
+
+func (s *xmlMarkupScanner) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b == '<' || b == '&' || (b == '>' && s.brackets >= 2) {
+			s.markup = true
+		}
+		if b == ']' {
+			s.brackets++
+		} else {
+			s.brackets = 0
+		}
+	}
+	if len(p) > 0 {
+		s.size += len(p)
+		s.last = p[len(p)-1]
+	}
+	return len(p), nil
+}
+
+// cdataWriter writes content inside a CDATA section like escapeXMLContent,
+// closing and reopening the section between the "]]" and ">" of every
+// "]]>" in the content, even if it is split across writes.
+type cdataWriter struct {
+	w        io.Writer
+	brackets int
+}
+
+func (c *cdataWriter) Write(p []byte) (int, error) {
+	start := 0
+	for i, b := range p {
+		if b == '>' && c.brackets >= 2 {
+			if _, err := c.w.Write(p[start:i]); err != nil {
+				return 0, err
+			}
+			if _, err := io.WriteString(c.w, cdataEnd+cdataStart); err != nil {
+				return 0, err
+			}
+			start = i
+		}
+		if b == ']' {
+			c.brackets++
+		} else {
+			c.brackets = 0
+		}
+	}
+	if _, err := c.w.Write(p[start:]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// escapeXMLContent makes arbitrary file content safe to embed as character data.
+// Content without markup characters is emitted verbatim to keep it readable.
+// Otherwise it is wrapped in a CDATA section, splitting the section wherever
+// the content itself contains the "]]>" terminator. Characters that are not
+// allowed in XML 1.0 documents are replaced with U+FFFD in either case.
+func escapeXMLContent(content []byte) []byte {
+	content = sanitizeXMLChars(content)
+
+	if !bytes.ContainsAny(content, "<&") && !bytes.Contains(content, []byte(cdataEnd)) {
+		return content
+	}
+
+	var out bytes.Buffer
+	out.WriteString(cdataStart)
+	for {
+		i := bytes.Index(content, []byte(cdataEnd))
+		if i < 0 {
+			break
+		}
+		// Close the section between "]]" and ">" so neither half forms a terminator.
+		out.Write(content[:i+2])
+		out.WriteString(cdataEnd + cdataStart)
+		content = content[i+2:]
+	}
+	out.Write(content)
+	out.WriteString(cdataEnd)
+	return out.Bytes()
+}
+
+// sanitizeXMLChars replaces invalid UTF-8 sequences and code points outside
+// the XML 1.0 Char production with the Unicode replacement character.
+func sanitizeXMLChars(content []byte) []byte {
+	valid := true
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRune(content[i:])
+		if (r == utf8.RuneError && size == 1) || !isXMLChar(r) {
+			valid = false
+			break
+		}
+		i += size
+	}
+	if valid {
+		return content
+	}
+
+	out := make([]byte, 0, len(content))
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRune(content[i:])
+		if (r == utf8.RuneError && size == 1) || !isXMLChar(r) {
+			r = utf8.RuneError
+		}
+		out = utf8.AppendRune(out, r)
+		i += size
+	}
+	return out
+}
+
+// isXMLChar reports whether r is a legal character in an XML 1.0 document.
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		(r >= 0x20 && r <= 0xD7FF) ||
+		(r >= 0xE000 && r <= 0xFFFD) ||
+		(r >= 0x10000 && r <= 0x10FFFF)
+}
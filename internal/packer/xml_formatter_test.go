@@ -0,0 +1,132 @@
+package packer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// xmlDocuments mirrors the structure produced by XMLFormatter for decoding in tests.
+type xmlDocuments struct {
+	Documents []struct {
+		Index   int    `xml:"index,attr"`
+		Source  string `xml:"source"`
+		Content string `xml:"document_content"`
+	} `xml:"document"`
+}
+
+func TestXMLFormatter(t *testing.T) {
+	testCases := []struct {
+		name        string
+		filename    string
+		content     string
+		wantContent string // Expected character data after XML decoding.
+		wantCDATA   bool
+	}{
+		{
+			name:        "plain content is written verbatim",
+			filename:    "main.go",
+			content:     "package main\n",
+			wantContent: "\npackage main\n",
+		},
+		{
+			name:        "markup characters use a CDATA section",
+			filename:    "index.html",
+			content:     "<p>a & b</p>\n",
+			wantContent: "\n<p>a & b</p>\n",
+			wantCDATA:   true,
+		},
+		{
+			name:        "CDATA terminator in content is split",
+			filename:    "tricky.txt",
+			content:     "x]]>y <z> ]]>",
+			wantContent: "\nx]]>y <z> ]]>\n",
+			wantCDATA:   true,
+		},
+		{
+			name:        "lone terminator without markup is still wrapped",
+			filename:    "a.txt",
+			content:     "]]>\n",
+			wantContent: "\n]]>\n",
+			wantCDATA:   true,
+		},
+		{
+			name:        "source path is escaped",
+			filename:    "dir/<odd>&name.txt",
+			content:     "ok\n",
+			wantContent: "\nok\n",
+		},
+		{
+			name:        "invalid XML characters are replaced",
+			filename:    "ctrl.txt",
+			content:     "a\x01b\xffc\n",
+			wantContent: "\na�b�c\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := NewXMLFormatter()
+			var out bytes.Buffer
+
+			begin, _ := f.Begin(Document{})
+			out.Write(begin)
+			formatted, err := f.Format(tc.filename, "text", []byte(tc.content))
+			if err != nil {
+				t.Fatalf("Format() returned an unexpected error: %v", err)
+			}
+			out.Write(formatted)
+			end, _ := f.End(Summary{})
+			out.Write(end)
+
+			if got := strings.Contains(out.String(), cdataStart); got != tc.wantCDATA {
+				t.Errorf("CDATA used = %v, want %v\noutput:\n%s", got, tc.wantCDATA, out.String())
+			}
+
+			var docs xmlDocuments
+			if err := xml.Unmarshal(out.Bytes(), &docs); err != nil {
+				t.Fatalf("output is not well-formed XML: %v\noutput:\n%s", err, out.String())
+			}
+			if len(docs.Documents) != 1 {
+				t.Fatalf("got %d documents, want 1", len(docs.Documents))
+			}
+			doc := docs.Documents[0]
+			if doc.Index != 1 {
+				t.Errorf("index = %d, want 1", doc.Index)
+			}
+			if doc.Source != tc.filename {
+				t.Errorf("source = %q, want %q", doc.Source, tc.filename)
+			}
+			if doc.Content != tc.wantContent {
+				t.Errorf("content = %q, want %q", doc.Content, tc.wantContent)
+			}
+		})
+	}
+}
+
+func TestXMLFormatter_Indexing(t *testing.T) {
+	f := NewXMLFormatter()
+
+	for run := 0; run < 2; run++ {
+		var out bytes.Buffer
+		begin, _ := f.Begin(Document{})
+		out.Write(begin)
+		for _, name := range []string{"a.go", "b.go", "c.go"} {
+			formatted, _ := f.Format(name, "go", []byte("package x\n"))
+			out.Write(formatted)
+		}
+		end, _ := f.End(Summary{})
+		out.Write(end)
+
+		var docs xmlDocuments
+		if err := xml.Unmarshal(out.Bytes(), &docs); err != nil {
+			t.Fatalf("output is not well-formed XML: %v", err)
+		}
+		for i, doc := range docs.Documents {
+			if doc.Index != i+1 {
+				t.Errorf("run %d: document %d has index %d, want %d", run, i, doc.Index, i+1)
+			}
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// ChangeStatus describes how a file differs between two states of a repository.
+type ChangeStatus int
+
+const (
+	Added ChangeStatus = iota + 1
+	Modified
+	Deleted
+	Renamed
+)
+
+// String returns the lowercase name of the status, e.g. "renamed".
+func (s ChangeStatus) String() string {
+	switch s {
+	case Added:
+		return "added"
+	case Modified:
+		return "modified"
+	case Deleted:
+		return "deleted"
+	case Renamed:
+		return "renamed"
+	}
+	return fmt.Sprintf("ChangeStatus(%d)", int(s))
+}
+
+// Change is a file that differs between two states of a repository.
+type Change struct {
+	// Path is the absolute path of the file in the worktree. Deleted files no longer exist.
+	Path string
+	// OldPath is the absolute previous path of a renamed file.
+	OldPath string
+	Status  ChangeStatus
+}
+
+// snapshot maps slash-separated paths relative to the repository root to
+// the hashes of their blobs.
+type snapshot map[string]plumbing.Hash
+
+// ChangesSince returns the files that differ between the tree of rev, which
+// may be any revision such as "main" or "HEAD~3", and the working tree of
+// the repository at root. Staged, unstaged and untracked changes are all
+// included; ignored files are not. If rev is "HEAD" and there are no
+// commits yet, every file is added.
+func ChangesSince(root, rev string) ([]Change, error) {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return nil, err
+	}
+	var from snapshot
+	if rev == "HEAD" {
+		from, err = headSnapshot(repo)
+	} else {
+		from, err = revisionSnapshot(repo, rev)
+	}
+	if err != nil {
+		return nil, err
+	}
+	to, err := worktreeSnapshot(repo, root)
+	if err != nil {
+		return nil, err
+	}
+	return diffSnapshots(repo, root, from, to)
+}
+
+// StagedChanges returns the files that differ between HEAD and the index of
+// the repository at root, like "git diff --staged".
+func StagedChanges(root string) ([]Change, error) {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return nil, err
+	}
+	from, err := headSnapshot(repo)
+	if err != nil {
+		return nil, err
+	}
+	to, err := indexSnapshot(repo)
+	if err != nil {
+		return nil, err
+	}
+	return diffSnapshots(repo, root, from, to)
+}
+
+// UnstagedChanges returns the files that differ between the index and the
+// working tree of the repository at root, like "git diff", plus untracked
+// files that are not ignored.
+func UnstagedChanges(root string) ([]Change, error) {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return nil, err
+	}
+	from, err := indexSnapshot(repo)
+	if err != nil {
+		return nil, err
+	}
+	to, err := worktreeSnapshot(repo, root)
+	if err != nil {
+		return nil, err
+	}
+	return diffSnapshots(repo, root, from, to)
+}
+
+// revisionSnapshot lists the files in the tree of the commit rev resolves to.
+func revisionSnapshot(repo *git.Repository, rev string) (snapshot, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve revision %q: %w", rev, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %w", hash, err)
+	}
+
+	files := make(snapshot)
+	err = tree.Files().ForEach(func(f *object.File) error {
+		files[f.Name] = f.Hash
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tree of %s: %w", hash, err)
+	}
+	return files, nil
+}
+
+// headSnapshot lists the files at HEAD, or none if there are no commits yet.
+func headSnapshot(repo *git.Repository) (snapshot, error) {
+	if _, err := repo.Head(); errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return make(snapshot), nil
+	}
+	return revisionSnapshot(repo, "HEAD")
+}
+
+// indexSnapshot lists the files in the index, skipping submodules and
+// files only marked with "git add -N".
+func indexSnapshot(repo *git.Repository) (snapshot, error) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	files := make(snapshot)
+	for _, e := range idx.Entries {
+		if e.Mode == filemode.Submodule || e.IntentToAdd {
+			continue
+		}
+		files[e.Name] = e.Hash
+	}
+	return files, nil
+}
+
+// worktreeSnapshot lists the files in the working tree: the index, updated
+// with the modified, deleted and untracked files reported by git status.
+// Only files that differ from the index are hashed.
+func worktreeSnapshot(repo *git.Repository, root string) (snapshot, error) {
+	files, err := indexSnapshot(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+
+	for path, s := range status {
+		switch s.Worktree {
+		case git.Unmodified:
+			continue
+		case git.Deleted:
+			delete(files, path)
+			continue
+		}
+		hash, err := hashFile(filepath.Join(root, filepath.FromSlash(path)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				delete(files, path)
+				continue
+			}
+			return nil, err
+		}
+		files[path] = hash
+	}
+	return files, nil
+}
+
+// hashFile computes the git blob hash of a file in the working tree.
+func hashFile(path string) (plumbing.Hash, error) {
+	content, err := readWorktreeFile(path)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return plumbing.ComputeHash(plumbing.BlobObject, content), nil
+}
+
+// readWorktreeFile reads the content git stores for a file in the working
+// tree. The blob of a symbolic link holds its target.
+func readWorktreeFile(path string) ([]byte, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(target), nil
+	}
+	return os.ReadFile(path)
+}
+
+// diffSnapshots compares two snapshots of the repository at root. A file
+// deleted from one path and added at another with identical or similar
+// content is reported as a single rename, using the similarity rename
+// detection of git. Changes are sorted by path.
+func diffSnapshots(repo *git.Repository, root string, from, to snapshot) ([]Change, error) {
+	abs := func(path string) string {
+		return filepath.Join(root, filepath.FromSlash(path))
+	}
+
+	var changes []Change
+	var added, deleted []string
+	for path, hash := range to {
+		old, ok := from[path]
+		switch {
+		case !ok:
+			added = append(added, path)
+		case old != hash:
+			changes = append(changes, Change{Path: abs(path), Status: Modified})
+		}
+	}
+	for path := range from {
+		if _, ok := to[path]; !ok {
+			deleted = append(deleted, path)
+		}
+	}
+
+	renames, err := detectRenames(repo, root, from, to, added, deleted)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range added {
+		if old, ok := renames[path]; ok {
+			changes = append(changes, Change{Path: abs(path), OldPath: abs(old), Status: Renamed})
+		} else {
+			changes = append(changes, Change{Path: abs(path), Status: Added})
+		}
+	}
+	renamed := make(map[string]bool, len(renames))
+	for _, old := range renames {
+		renamed[old] = true
+	}
+	for _, path := range deleted {
+		if !renamed[path] {
+			changes = append(changes, Change{Path: abs(path), Status: Deleted})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// detectRenames pairs added and deleted files into renames with the rename
+// detection of go-git, and returns the previous path of each renamed file
+// by its new path. The blobs of the files are copied to an in-memory store,
+// as files in the working tree may have no blob in the repository.
+func detectRenames(repo *git.Repository, root string, from, to snapshot, added, deleted []string) (map[string]string, error) {
+	if len(added) == 0 || len(deleted) == 0 {
+		return nil, nil
+	}
+
+	store := memory.NewStorage()
+	tree, err := emptyTree(store)
+	if err != nil {
+		return nil, err
+	}
+	entry := func(path string, hash plumbing.Hash) (object.ChangeEntry, error) {
+		if err := copyBlob(repo, store, root, path, hash); err != nil {
+			return object.ChangeEntry{}, err
+		}
+		return object.ChangeEntry{
+			Name:      path,
+			Tree:      tree,
+			TreeEntry: object.TreeEntry{Name: path, Mode: filemode.Regular, Hash: hash},
+		}, nil
+	}
+
+	var changes object.Changes
+	for _, path := range added {
+		e, err := entry(path, to[path])
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, &object.Change{To: e})
+	}
+	for _, path := range deleted {
+		e, err := entry(path, from[path])
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, &object.Change{From: e})
+	}
+
+	changes, err = object.DetectRenames(changes, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect renames: %w", err)
+	}
+	renames := make(map[string]string)
+	for _, c := range changes {
+		if c.From.Name != "" && c.To.Name != "" {
+			renames[c.To.Name] = c.From.Name
+		}
+	}
+	return renames, nil
+}
+
+// emptyTree stores an empty tree in store and returns it, to give changes
+// a tree through which their blobs are read from store.
+func emptyTree(store *memory.Storage) (*object.Tree, error) {
+	obj := store.NewEncodedObject()
+	if err := (&object.Tree{}).Encode(obj); err != nil {
+		return nil, err
+	}
+	hash, err := store.SetEncodedObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	return object.GetTree(store, hash)
+}
+
+// copyBlob copies the blob with the given hash from the repository to
+// store, or, if the repository does not have it, reads it from the file at
+// path in the working tree.
+func copyBlob(repo *git.Repository, store *memory.Storage, root, path string, hash plumbing.Hash) error {
+	obj, err := repo.Storer.EncodedObject(plumbing.BlobObject, hash)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		content, err := readWorktreeFile(filepath.Join(root, filepath.FromSlash(path)))
+		if err != nil {
+			return err
+		}
+		blob := &plumbing.MemoryObject{}
+		blob.SetType(plumbing.BlobObject)
+		if _, err := blob.Write(content); err != nil {
+			return err
+		}
+		obj = blob
+	} else if err != nil {
+		return fmt.Errorf("failed to read blob of %s: %w", path, err)
+	}
+	_, err = store.SetEncodedObject(obj)
+	return err
+}
@@ -0,0 +1,202 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestChanges(t *testing.T) {
+	root, cleanup := setupTestEnvironment(t, true)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	stage := func(names ...string) {
+		t.Helper()
+		for _, name := range names {
+			if _, err := wt.Add(name); err != nil {
+				t.Fatalf("failed to stage %s: %v", name, err)
+			}
+		}
+	}
+	commit := func(msg string) string {
+		t.Helper()
+		hash, err := wt.Commit(msg, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+		return hash.String()
+	}
+
+	write(".gitignore", "*.log\n")
+	write("a.txt", "a")
+	write("b.txt", "b")
+	write("c.txt", "c")
+	write("d.txt", "d")
+	stage(".gitignore", "a.txt", "b.txt", "c.txt", "d.txt")
+	base := commit("base")
+
+	write("a.txt", "a2")
+	if _, err := wt.Move("b.txt", "bb.txt"); err != nil {
+		t.Fatalf("failed to move b.txt: %v", err)
+	}
+	stage("a.txt")
+	commit("edit")
+
+	// Staged changes.
+	if _, err := wt.Remove("c.txt"); err != nil {
+		t.Fatalf("failed to remove c.txt: %v", err)
+	}
+	write("e.txt", "e")
+	stage("e.txt")
+
+	// Unstaged changes.
+	write("d.txt", "d2")
+	write("f.txt", "f")
+	write("g.log", "ignored")
+
+	abs := func(name string) string { return filepath.Join(root, name) }
+
+	testCases := []struct {
+		name    string
+		changes func() ([]Change, error)
+		want    []Change
+	}{
+		{
+			name:    "since a revision",
+			changes: func() ([]Change, error) { return ChangesSince(root, base) },
+			want: []Change{
+				{Path: abs("a.txt"), Status: Modified},
+				{Path: abs("bb.txt"), OldPath: abs("b.txt"), Status: Renamed},
+				{Path: abs("c.txt"), Status: Deleted},
+				{Path: abs("d.txt"), Status: Modified},
+				{Path: abs("e.txt"), Status: Added},
+				{Path: abs("f.txt"), Status: Added},
+			},
+		},
+		{
+			name:    "staged",
+			changes: func() ([]Change, error) { return StagedChanges(root) },
+			want: []Change{
+				{Path: abs("c.txt"), Status: Deleted},
+				{Path: abs("e.txt"), Status: Added},
+			},
+		},
+		{
+			name:    "unstaged",
+			changes: func() ([]Change, error) { return UnstagedChanges(root) },
+			want: []Change{
+				{Path: abs("d.txt"), Status: Modified},
+				{Path: abs("f.txt"), Status: Added},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.changes()
+			if err != nil {
+				t.Fatalf("returned an unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("unknown revision", func(t *testing.T) {
+		if _, err := ChangesSince(root, "no-such-branch"); err == nil {
+			t.Error("ChangesSince() expected an error for an unknown revision")
+		}
+	})
+}
+
+func TestChanges_RenameWithEdits(t *testing.T) {
+	root, cleanup := setupTestEnvironment(t, true)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+
+	var content strings.Builder
+	for i := range 20 {
+		fmt.Fprintf(&content, "line %d of the original file\n", i)
+	}
+	if err := os.WriteFile(filepath.Join(root, "old.txt"), []byte(content.String()), 0644); err != nil {
+		t.Fatalf("failed to write old.txt: %v", err)
+	}
+	if _, err := wt.Add("old.txt"); err != nil {
+		t.Fatalf("failed to stage old.txt: %v", err)
+	}
+	_, err = wt.Commit("base", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	// Rename the file and edit it without staging either change.
+	if err := os.Remove(filepath.Join(root, "old.txt")); err != nil {
+		t.Fatalf("failed to remove old.txt: %v", err)
+	}
+	edited := strings.Replace(content.String(), "line 3 ", "line three ", 1)
+	if err := os.WriteFile(filepath.Join(root, "new.txt"), []byte(edited), 0644); err != nil {
+		t.Fatalf("failed to write new.txt: %v", err)
+	}
+
+	got, err := ChangesSince(root, "HEAD")
+	if err != nil {
+		t.Fatalf("ChangesSince() returned an unexpected error: %v", err)
+	}
+	want := []Change{{Path: filepath.Join(root, "new.txt"), OldPath: filepath.Join(root, "old.txt"), Status: Renamed}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangesSince() = %+v, want %+v", got, want)
+	}
+}
+
+func TestChangesSince_NoCommits(t *testing.T) {
+	root, cleanup := setupTestEnvironment(t, true)
+	defer cleanup()
+
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatalf("failed to write a.txt: %v", err)
+	}
+
+	got, err := ChangesSince(root, "HEAD")
+	if err != nil {
+		t.Fatalf("ChangesSince() returned an unexpected error: %v", err)
+	}
+	want := []Change{{Path: filepath.Join(root, "a.txt"), Status: Added}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangesSince() = %+v, want %+v", got, want)
+	}
+}
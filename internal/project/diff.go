@@ -0,0 +1,219 @@
+package project
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// Differ produces unified diffs between the tree of a git revision and the
+// working tree, without running git.
+type Differ struct {
+	root string
+	tree *object.Tree
+}
+
+// NewDiffer creates a Differ comparing the working tree of the repository at
+// root with the tree of the commit rev resolves to.
+func NewDiffer(root, rev string) (*Differ, error) {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve revision %q: %w", rev, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %w", hash, err)
+	}
+	return &Differ{root: root, tree: tree}, nil
+}
+
+// Diff returns the unified diff, in "git diff" format, of the file at the
+// absolute path in the working tree against its version at the revision,
+// where it was located at oldPath. A file missing on either side is diffed
+// as added or deleted. The result is empty if the versions are identical.
+func (d *Differ) Diff(path, oldPath string) ([]byte, error) {
+	from, err := d.revisionFile(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	to, err := d.worktreeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if from == nil && to == nil {
+		return nil, nil
+	}
+	if from != nil && to != nil && from.hash == to.hash && from.path == to.path {
+		return nil, nil
+	}
+
+	patch := &filePatch{from: from, to: to}
+	if !patch.IsBinary() {
+		var src, dst string
+		if from != nil {
+			src = string(from.content)
+		}
+		if to != nil {
+			dst = string(to.content)
+		}
+		for _, change := range diff.Do(src, dst) {
+			patch.chunks = append(patch.chunks, chunk{content: change.Text, op: chunkOperation(change.Type)})
+		}
+	}
+
+	var out bytes.Buffer
+	if err := fdiff.NewUnifiedEncoder(&out, fdiff.DefaultContextLines).Encode(patch); err != nil {
+		return nil, fmt.Errorf("failed to encode diff: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// relativePath converts an absolute worktree path to a slash-separated path
+// relative to the repository root.
+func (d *Differ) relativePath(path string) (string, error) {
+	rel, err := filepath.Rel(d.root, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// revisionFile reads a file from the revision's tree, or returns nil if it does not exist there.
+func (d *Differ) revisionFile(path string) (*diffFile, error) {
+	rel, err := d.relativePath(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := d.tree.File(rel)
+	if err != nil {
+		if errors.Is(err, object.ErrFileNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s from revision: %w", rel, err)
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from revision: %w", rel, err)
+	}
+	binary, err := f.IsBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &diffFile{path: rel, hash: f.Hash, mode: f.Mode, content: []byte(content), binary: binary}, nil
+}
+
+// worktreeFile reads a file from the working tree, or returns nil if it does not exist.
+func (d *Differ) worktreeFile(path string) (*diffFile, error) {
+	rel, err := d.relativePath(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	mode, err := filemode.NewFromOSFileMode(info.Mode())
+	if err != nil {
+		return nil, err
+	}
+
+	var content []byte
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		content = []byte(target)
+	} else if content, err = os.ReadFile(path); err != nil {
+		return nil, err
+	}
+
+	return &diffFile{
+		path:    rel,
+		hash:    plumbing.ComputeHash(plumbing.BlobObject, content),
+		mode:    mode,
+		content: content,
+		binary:  bytes.IndexByte(content, 0) >= 0,
+	}, nil
+}
+
+// chunkOperation maps a diffmatchpatch operation to a diff chunk operation.
+func chunkOperation(op diffmatchpatch.Operation) fdiff.Operation {
+	switch op {
+	case diffmatchpatch.DiffInsert:
+		return fdiff.Add
+	case diffmatchpatch.DiffDelete:
+		return fdiff.Delete
+	}
+	return fdiff.Equal
+}
+
+// diffFile is one side of a file patch. It implements diff.File.
+type diffFile struct {
+	path    string
+	hash    plumbing.Hash
+	mode    filemode.FileMode
+	content []byte
+	binary  bool
+}
+
+func (f *diffFile) Hash() plumbing.Hash     { return f.hash }
+func (f *diffFile) Mode() filemode.FileMode { return f.mode }
+func (f *diffFile) Path() string            { return f.path }
+
+// chunk implements diff.Chunk.
+type chunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c chunk) Content() string       { return c.content }
+func (c chunk) Type() fdiff.Operation { return c.op }
+
+// filePatch is the patch of a single file. It implements both diff.FilePatch
+// and diff.Patch.
+type filePatch struct {
+	from, to *diffFile
+	chunks   []fdiff.Chunk
+}
+
+func (p *filePatch) IsBinary() bool {
+	return (p.from != nil && p.from.binary) || (p.to != nil && p.to.binary)
+}
+
+// Files returns the sides of the patch. Missing sides must be untyped nil
+// interfaces for the encoder to recognize added and deleted files.
+func (p *filePatch) Files() (from, to fdiff.File) {
+	if p.from != nil {
+		from = p.from
+	}
+	if p.to != nil {
+		to = p.to
+	}
+	return from, to
+}
+
+func (p *filePatch) Chunks() []fdiff.Chunk          { return p.chunks }
+func (p *filePatch) FilePatches() []fdiff.FilePatch { return []fdiff.FilePatch{p} }
+func (p *filePatch) Message() string                { return "" }
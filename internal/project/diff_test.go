@@ -0,0 +1,110 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestDiffer_Diff(t *testing.T) {
+	root, cleanup := setupTestEnvironment(t, true)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+
+	abs := func(name string) string { return filepath.Join(root, name) }
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(abs(name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	write("a.txt", "one\ntwo\nthree\n")
+	write("gone.txt", "bye\n")
+	write("same.txt", "same\n")
+	write("old.txt", "moved\n")
+	for _, name := range []string{"a.txt", "gone.txt", "same.txt", "old.txt"} {
+		if _, err := wt.Add(name); err != nil {
+			t.Fatalf("failed to stage %s: %v", name, err)
+		}
+	}
+	_, err = wt.Commit("base", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	write("a.txt", "one\n2\nthree\n")
+	write("new.txt", "hello\n")
+	write("moved.txt", "moved!\n")
+	if err := os.Remove(abs("gone.txt")); err != nil {
+		t.Fatalf("failed to remove gone.txt: %v", err)
+	}
+
+	differ, err := NewDiffer(root, "HEAD")
+	if err != nil {
+		t.Fatalf("NewDiffer() returned an unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name          string
+		path, oldPath string
+		want          []string
+	}{
+		{
+			name: "modified",
+			path: abs("a.txt"), oldPath: abs("a.txt"),
+			want: []string{"diff --git a/a.txt b/a.txt\n", "--- a/a.txt\n+++ b/a.txt\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n"},
+		},
+		{
+			name: "added",
+			path: abs("new.txt"), oldPath: abs("new.txt"),
+			want: []string{"new file mode 100644\n", "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+hello\n"},
+		},
+		{
+			name: "deleted",
+			path: abs("gone.txt"), oldPath: abs("gone.txt"),
+			want: []string{"deleted file mode 100644\n", "--- a/gone.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n"},
+		},
+		{
+			name: "renamed",
+			path: abs("moved.txt"), oldPath: abs("old.txt"),
+			want: []string{"diff --git a/old.txt b/moved.txt\n", "-moved\n+moved!\n"},
+		},
+		{
+			name: "unchanged",
+			path: abs("same.txt"), oldPath: abs("same.txt"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := differ.Diff(tc.path, tc.oldPath)
+			if err != nil {
+				t.Fatalf("Diff() returned an unexpected error: %v", err)
+			}
+			if tc.want == nil && len(got) != 0 {
+				t.Errorf("Diff() = %q, want no diff", got)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("Diff() = %q, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
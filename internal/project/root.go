@@ -7,8 +7,17 @@ import (
 	"path/filepath"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 )
 
+// shortHashLength is the number of hex digits used for abbreviated commit hashes.
+const shortHashLength = 7
+
+// ShortHash returns the abbreviated form of a commit hash.
+func ShortHash(hash plumbing.Hash) string {
+	return hash.String()[:shortHashLength]
+}
+
 // FindRoot searches upwards from a given path to find the root of a Git repository.
 // It returns the absolute path to the repository root and a boolean `isRepo` which is true
 // if a repository was found. If no repository is found, it returns a sensible fallback
@@ -36,6 +45,25 @@ func FindRoot(path string) (root string, isRepo bool, err error) {
 	return wt.Filesystem.Root(), true, nil
 }
 
+// HeadCommit returns the abbreviated hash of the commit checked out in the
+// repository at root. It returns an empty string if the repository has no commits yet.
+func HeadCommit(root string) (string, error) {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	return ShortHash(head.Hash()), nil
+}
+
 // fallbackRoot determines a sensible root directory when not inside a git repo.
 func fallbackRoot(path string) (string, error) {
 	info, err := os.Stat(path)
@@ -4,8 +4,10 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
 // setupTestEnvironment creates a temporary directory and initializes a git repository if requested.
@@ -156,3 +158,49 @@ func TestFallbackRoot(t *testing.T) {
 		}
 	})
 }
+
+func TestHeadCommit(t *testing.T) {
+	repoRoot, cleanup := setupTestEnvironment(t, true)
+	defer cleanup()
+
+	t.Run("repository without commits", func(t *testing.T) {
+		commit, err := HeadCommit(repoRoot)
+		if err != nil {
+			t.Errorf("HeadCommit(%q) returned error: %v", repoRoot, err)
+		}
+		if commit != "" {
+			t.Errorf("HeadCommit(%q) got %q, want empty string", repoRoot, commit)
+		}
+	})
+
+	t.Run("repository with a commit", func(t *testing.T) {
+		repo, err := git.PlainOpen(repoRoot)
+		if err != nil {
+			t.Fatalf("failed to open repo: %v", err)
+		}
+		wt, err := repo.Worktree()
+		if err != nil {
+			t.Fatalf("failed to get worktree: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(repoRoot, "a.txt"), []byte("a"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := wt.Add("a.txt"); err != nil {
+			t.Fatalf("failed to stage file: %v", err)
+		}
+		hash, err := wt.Commit("initial", &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+
+		commit, err := HeadCommit(repoRoot)
+		if err != nil {
+			t.Errorf("HeadCommit(%q) returned error: %v", repoRoot, err)
+		}
+		if want := hash.String()[:7]; commit != want {
+			t.Errorf("HeadCommit(%q) got %q, want %q", repoRoot, commit, want)
+		}
+	})
+}
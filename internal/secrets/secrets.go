@@ -0,0 +1,352 @@
+// Package secrets detects and redacts credentials in text, such as cloud
+// access keys, API tokens, private keys and passwords.
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+)
+
+// Finding is a secret detected in scanned content.
+type Finding struct {
+	// Kind names the type of secret, e.g. "aws-access-key-id".
+	Kind string
+	// Line is the 1-based line of scanned content on which the secret starts.
+	Line int
+
+	start, end int
+}
+
+// rule matches one kind of secret. If the pattern has a capture group, only
+// the first participating group is the secret; the rest of the match is context.
+type rule struct {
+	kind    string
+	pattern *regexp.Regexp
+	// accept, if set, filters out matches that are not secrets.
+	accept func(secret []byte) bool
+	// languages, if set, restricts the rule to content in these languages.
+	languages map[string]bool
+}
+
+// assignmentKey matches names whose values are conventionally secret.
+// A bare "token" is left out, as it names token counts in source code.
+const assignmentKey = `(?i)[\w.-]*(?:password|passwd|pwd|secret|api_?key|access_?key|(?:api|auth|access)_?token)[\w.-]*`
+
+// configLanguages lists the languages of env, INI, YAML and shell files,
+// where secrets are commonly assigned without quotes.
+var configLanguages = map[string]bool{
+	"dotenv":          true,
+	"ini":             true,
+	"java-properties": true,
+	"toml":            true,
+	"yaml":            true,
+	"shell":           true,
+}
+
+// rules are applied in order. A match overlapping an earlier one is ignored.
+var rules = []rule{
+	{
+		kind:    "private-key",
+		pattern: regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY( BLOCK)?-----[\s\S]*?-----END[A-Z ]*PRIVATE KEY( BLOCK)?-----`),
+	},
+	{
+		kind:    "aws-access-key-id",
+		pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`),
+	},
+	{
+		kind:    "aws-secret-access-key",
+		pattern: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*(?::=|=|:)\s*["']?([A-Za-z0-9/+=]{40})\b`),
+	},
+	{
+		kind:    "github-token",
+		pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`),
+	},
+	{
+		kind:    "jwt",
+		pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`),
+	},
+	{
+		// Quoted values of assignments such as `password = "..."` or `"token": "..."`.
+		kind:    "password",
+		pattern: regexp.MustCompile(assignmentKey + `["']?\s*(?::=|=|:)\s*(?:"([^"\s]{4,})"|'([^'\s]{4,})')`),
+		accept:  isPasswordLike,
+	},
+	{
+		// Unquoted values in .env, INI and YAML style lines such as `DB_PASSWORD=...`.
+		kind:      "password",
+		pattern:   regexp.MustCompile(`(?m)^[ \t]*(?:export[ \t]+)?` + assignmentKey + `[ \t]*[=:][ \t]*([^\s'"#()\[\]]{4,})[ \t]*\r?$`),
+		accept:    isUnquotedPasswordLike,
+		languages: configLanguages,
+	},
+	{
+		kind:    "high-entropy-string",
+		pattern: regexp.MustCompile("[\"'`]([A-Za-z0-9+/=_-]{24,})[\"'`]"),
+		accept:  isHighEntropy,
+	},
+}
+
+// Scan returns the secrets found in content, written in the given
+// language, in order of appearance.
+func Scan(language string, content []byte) []Finding {
+	var findings []Finding
+	for _, r := range rules {
+		if r.languages != nil && !r.languages[language] {
+			continue
+		}
+		for _, m := range r.pattern.FindAllSubmatchIndex(content, -1) {
+			start, end := secretSpan(m)
+			if r.accept != nil && !r.accept(content[start:end]) {
+				continue
+			}
+			if overlaps(findings, start, end) {
+				continue
+			}
+			findings = append(findings, Finding{Kind: r.kind, start: start, end: end})
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].start < findings[j].start
+	})
+
+	line, offset := 1, 0
+	for i := range findings {
+		line += bytes.Count(content[offset:findings[i].start], []byte("\n"))
+		offset = findings[i].start
+		findings[i].Line = line
+	}
+	return findings
+}
+
+// Redact replaces every secret found in content, written in the given
+// language, with "[REDACTED:kind]" and returns the new content along with
+// the findings. The replacement of a secret spanning several lines, such
+// as a private key, keeps its line breaks, so that the following lines
+// keep their numbers. If nothing is found, content is returned as is.
+func Redact(language string, content []byte) ([]byte, []Finding) {
+	findings := Scan(language, content)
+	if len(findings) == 0 {
+		return content, nil
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(content))
+	last := 0
+	for _, f := range findings {
+		out.Write(content[last:f.start])
+		out.WriteString("[REDACTED:" + f.Kind + "]")
+		out.Write(bytes.Repeat([]byte("\n"), bytes.Count(content[f.start:f.end], []byte("\n"))))
+		last = f.end
+	}
+	out.Write(content[last:])
+	return out.Bytes(), findings
+}
+
+// privateKeyBegin and privateKeyEnd match the lines delimiting a private key block.
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY( BLOCK)?-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END[A-Z ]*PRIVATE KEY( BLOCK)?-----`)
+)
+
+// maxLookahead caps the content a Reader holds to redact at once. Longer
+// lines are redacted in pieces of this size, and the rest of a longer
+// private key block is dropped as it is read.
+const maxLookahead = 64 << 10
+
+// Reader redacts secrets like Redact as content is read from an underlying
+// reader. Content is redacted a line at a time, except private key blocks,
+// which are redacted whole.
+type Reader struct {
+	language string
+	src      *bufio.Reader
+	found    func(Finding)
+	// line is the number of the first line of the next chunk.
+	line int
+	// inKey is true while dropping the rest of a private key block longer
+	// than maxLookahead.
+	inKey bool
+	// buf holds redacted content not yet returned.
+	buf []byte
+	err error
+}
+
+// NewReader returns a Reader redacting content read from r, written in the
+// given language. If found is not nil, it is called for every secret, with
+// its line in the content.
+func NewReader(language string, r io.Reader, found func(Finding)) *Reader {
+	return &Reader{language: language, src: bufio.NewReaderSize(r, maxLookahead), found: found, line: 1}
+}
+
+// Read reads redacted content into p.
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.fill()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// fill reads and redacts the next line, or the next private key block.
+func (r *Reader) fill() {
+	chunk, err := r.readLine()
+	if r.inKey {
+		r.dropKey(chunk, err)
+		return
+	}
+
+	if err == nil && privateKeyBegin.Match(chunk) && !privateKeyEnd.Match(chunk) {
+		ended := false
+		for err == nil && !ended && len(chunk) < maxLookahead {
+			var line []byte
+			line, err = r.readLine()
+			chunk = append(chunk, line...)
+			ended = privateKeyEnd.Match(line)
+		}
+		if !ended && err == nil {
+			r.startKey(chunk)
+			return
+		}
+	}
+	r.redact(chunk, err)
+}
+
+// readLine reads the next line, or the next maxLookahead bytes of a longer line.
+func (r *Reader) readLine() ([]byte, error) {
+	line, err := r.src.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		err = nil
+	}
+	return bytes.Clone(line), err
+}
+
+// redact appends chunk, redacted, to the content not yet returned.
+func (r *Reader) redact(chunk []byte, err error) {
+	redacted, findings := Redact(r.language, chunk)
+	if r.found != nil {
+		for _, f := range findings {
+			f.Line += r.line - 1
+			r.found(f)
+		}
+	}
+	r.buf = append(r.buf, redacted...)
+	r.line += bytes.Count(chunk, []byte("\n"))
+	r.err = err
+}
+
+// startKey redacts the start of a private key block longer than
+// maxLookahead, whose end has not been read yet. Like Redact, it keeps the
+// line breaks of the block.
+func (r *Reader) startKey(chunk []byte) {
+	begin := privateKeyBegin.FindIndex(chunk)[0]
+	r.redact(chunk[:begin], nil)
+	if r.found != nil {
+		r.found(Finding{Kind: "private-key", Line: r.line})
+	}
+	r.buf = append(r.buf, "[REDACTED:private-key]"...)
+	r.keepLineBreaks(chunk[begin:])
+	r.inKey = true
+}
+
+// dropKey drops the content of a long private key block up to its end,
+// keeping its line breaks, and redacts what follows the end.
+func (r *Reader) dropKey(chunk []byte, err error) {
+	if loc := privateKeyEnd.FindIndex(chunk); loc != nil {
+		r.inKey = false
+		r.keepLineBreaks(chunk[:loc[1]])
+		r.redact(chunk[loc[1]:], err)
+		return
+	}
+	r.keepLineBreaks(chunk)
+	r.err = err
+}
+
+// keepLineBreaks appends one newline for each in dropped content.
+func (r *Reader) keepLineBreaks(dropped []byte) {
+	n := bytes.Count(dropped, []byte("\n"))
+	r.buf = append(r.buf, bytes.Repeat([]byte("\n"), n)...)
+	r.line += n
+}
+
+// secretSpan returns the span of the first participating capture group of a
+// match, or of the whole match if the pattern has no groups that matched.
+func secretSpan(m []int) (int, int) {
+	for i := 2; i+1 < len(m); i += 2 {
+		if m[i] >= 0 {
+			return m[i], m[i+1]
+		}
+	}
+	return m[0], m[1]
+}
+
+// overlaps reports whether the span [start, end) overlaps any finding.
+func overlaps(findings []Finding, start, end int) bool {
+	for _, f := range findings {
+		if start < f.end && f.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// isPasswordLike reports whether an assigned value may be a password.
+// Values without digits or symbols are likely identifiers or labels, such
+// as `flag := "password"`, rather than secrets.
+func isPasswordLike(value []byte) bool {
+	return bytes.ContainsAny(value, "0123456789!@$%^&*+/=~{}")
+}
+
+// isUnquotedPasswordLike reports whether an unquoted assigned value may be a
+// password. Values ending in code punctuation, as in a struct literal field
+// `Tokens: 4000,`, are code rather than configuration.
+func isUnquotedPasswordLike(value []byte) bool {
+	for _, suffix := range []string{",", ";", "{}", ")"} {
+		if bytes.HasSuffix(value, []byte(suffix)) {
+			return false
+		}
+	}
+	return isPasswordLike(value)
+}
+
+// minEntropy is the Shannon entropy, in bits per character, above which a
+// long token is considered random. Identifiers and words stay well below it,
+// while base64-encoded keys of 24 characters or more typically exceed it.
+const minEntropy = 4.0
+
+// isHighEntropy reports whether s looks like a random key: it mixes upper-
+// and lowercase letters and digits, and its entropy exceeds minEntropy.
+func isHighEntropy(s []byte) bool {
+	var upper, lower, digit bool
+	var counts [256]int
+	for _, c := range s {
+		counts[c]++
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+
+	entropy := 0.0
+	for _, n := range counts {
+		if n > 0 {
+			p := float64(n) / float64(len(s))
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy > minEntropy
+}
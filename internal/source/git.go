@@ -0,0 +1,190 @@
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/jbwfu/syntex/internal/project"
+)
+
+// Git is a Source reading from the tree of a git revision, as if it were
+// checked out in place of the working tree. Symbolic links and submodules
+// in the tree are left out. It is safe for concurrent use.
+type Git struct {
+	root   string
+	cwd    string
+	commit plumbing.Hash
+	when   time.Time
+	// files maps slash-separated paths relative to root to their blobs.
+	files map[string]*object.File
+	// dirs maps the slash-separated paths of all directories, with "." for
+	// root, to the sorted names of their entries.
+	dirs map[string][]string
+
+	// mu serializes object reads, as the repository storage is not safe
+	// for concurrent use.
+	mu sync.Mutex
+}
+
+// NewGit creates a Source reading the tree of the commit rev resolves to in
+// the repository at root. Rev may be a commit hash, a tag or a branch.
+func NewGit(root, rev string) (*Git, error) {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve revision %q: %w", rev, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %w", hash, err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Git{
+		root:   root,
+		cwd:    cwd,
+		commit: commit.Hash,
+		when:   commit.Committer.When,
+		files:  make(map[string]*object.File),
+		dirs:   map[string][]string{".": nil},
+	}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if f.Mode == filemode.Symlink || f.Mode == filemode.Submodule {
+			return nil
+		}
+		g.files[f.Name] = f
+		// Register the file with its parent, and new directories with theirs.
+		for name := f.Name; ; name = path.Dir(name) {
+			parent := path.Dir(name)
+			_, known := g.dirs[parent]
+			g.dirs[parent] = append(g.dirs[parent], path.Base(name))
+			if known {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tree of %s: %w", hash, err)
+	}
+	for _, entries := range g.dirs {
+		sort.Strings(entries)
+	}
+	return g, nil
+}
+
+// Commit returns the abbreviated hash of the commit the revision resolved to.
+func (g *Git) Commit() string {
+	return project.ShortHash(g.commit)
+}
+
+// Open opens the named file in the revision's tree. The blob is read into
+// memory at once.
+func (g *Git) Open(name string) (io.ReadCloser, error) {
+	rel, _ := g.relative(name)
+	f, ok := g.files[rel]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	r, err := f.Reader()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// Stat returns information about the named file or directory in the revision's tree.
+func (g *Git) Stat(name string) (fs.FileInfo, error) {
+	rel, ok := g.relative(name)
+	if ok {
+		if _, ok := g.dirs[rel]; ok {
+			return &fileInfo{name: path.Base(rel), mode: fs.ModeDir | 0755, modTime: g.when}, nil
+		}
+		if f, ok := g.files[rel]; ok {
+			mode, err := f.Mode.ToOSFileMode()
+			if err != nil {
+				return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+			}
+			return &fileInfo{name: path.Base(rel), size: f.Size, mode: mode, modTime: g.when}, nil
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir returns the entries of the named directory in the revision's tree, sorted by name.
+func (g *Git) ReadDir(name string) ([]fs.DirEntry, error) {
+	rel, ok := g.relative(name)
+	children, isDir := g.dirs[rel]
+	if !ok || !isDir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		info, err := g.Stat(filepath.Join(name, child))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	return entries, nil
+}
+
+// relative converts a filesystem path to a slash-separated path relative to
+// the repository root. It returns false if the path lies outside the root.
+func (g *Git) relative(name string) (string, bool) {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(g.cwd, name)
+	}
+	rel, err := filepath.Rel(g.root, filepath.Clean(name))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// fileInfo describes a file or directory of a git tree. It implements fs.FileInfo.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *fileInfo) Name() string       { return i.name }
+func (i *fileInfo) Size() int64        { return i.size }
+func (i *fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *fileInfo) ModTime() time.Time { return i.modTime }
+func (i *fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *fileInfo) Sys() any           { return nil }
@@ -0,0 +1,142 @@
+package source
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/jbwfu/syntex/internal/project"
+)
+
+// commitFiles initializes a repository at root, commits the given files and
+// returns the commit hash.
+func commitFiles(t *testing.T, root string, files map[string]string) plumbing.Hash {
+	t.Helper()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatalf("failed to stage %s: %v", name, err)
+		}
+	}
+	hash, err := wt.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	return hash
+}
+
+func TestGit(t *testing.T) {
+	root := t.TempDir()
+	hash := commitFiles(t, root, map[string]string{
+		"main.go":         "package main\n",
+		"src/app.go":      "package src\n",
+		"src/util/str.go": "package util\n",
+		"README.md":       "# demo\n",
+	})
+
+	// The working tree no longer matches the revision.
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package changed\n"), 0644); err != nil {
+		t.Fatalf("failed to modify main.go: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "untracked.go"), []byte("package x\n"), 0644); err != nil {
+		t.Fatalf("failed to write untracked.go: %v", err)
+	}
+	t.Chdir(filepath.Join(root, "src"))
+
+	src, err := NewGit(root, "HEAD")
+	if err != nil {
+		t.Fatalf("NewGit() returned an unexpected error: %v", err)
+	}
+	if want := project.ShortHash(hash); src.Commit() != want {
+		t.Errorf("Commit() = %q, want %q", src.Commit(), want)
+	}
+
+	t.Run("read file from revision", func(t *testing.T) {
+		content, err := ReadFile(src, "../main.go")
+		if err != nil {
+			t.Fatalf("ReadFile() returned an unexpected error: %v", err)
+		}
+		if string(content) != "package main\n" {
+			t.Errorf("ReadFile() = %q, want the committed content", content)
+		}
+	})
+
+	t.Run("missing files", func(t *testing.T) {
+		for _, name := range []string{"../untracked.go", "util", filepath.Join(root, "..", "outside.go")} {
+			if _, err := src.Open(name); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Open(%q) error = %v, want fs.ErrNotExist", name, err)
+			}
+		}
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		info, err := src.Stat("util")
+		if err != nil || !info.IsDir() {
+			t.Errorf("Stat(util) = %v, %v, want a directory", info, err)
+		}
+		info, err = src.Stat(filepath.Join(root, "README.md"))
+		if err != nil || info.IsDir() || info.Size() != int64(len("# demo\n")) {
+			t.Errorf("Stat(README.md) = %v, %v, want a 7 byte file", info, err)
+		}
+		if _, err := src.Stat("../untracked.go"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Stat(untracked.go) error = %v, want fs.ErrNotExist", err)
+		}
+	})
+
+	t.Run("read dir", func(t *testing.T) {
+		testCases := []struct {
+			dir  string
+			want []string
+		}{
+			{dir: ".", want: []string{"app.go", "util"}},
+			{dir: "..", want: []string{"README.md", "main.go", "src"}},
+			{dir: filepath.Join(root, "src", "util"), want: []string{"str.go"}},
+		}
+		for _, tc := range testCases {
+			entries, err := src.ReadDir(tc.dir)
+			if err != nil {
+				t.Fatalf("ReadDir(%q) returned an unexpected error: %v", tc.dir, err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.Name())
+				if wantDir := entry.Name() == "src" || entry.Name() == "util"; entry.IsDir() != wantDir {
+					t.Errorf("ReadDir(%q): %s IsDir() = %v, want %v", tc.dir, entry.Name(), entry.IsDir(), wantDir)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ReadDir(%q) = %v, want %v", tc.dir, got, tc.want)
+			}
+		}
+		if _, err := src.ReadDir(filepath.Join(root, "..")); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("ReadDir() outside the repository error = %v, want fs.ErrNotExist", err)
+		}
+	})
+
+	if _, err := NewGit(root, "no-such-branch"); err == nil {
+		t.Error("NewGit() with an unknown revision should fail")
+	}
+}
@@ -0,0 +1,53 @@
+// Package source abstracts where packed files are read from: the local
+// filesystem, or the tree of a git revision.
+package source
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Source lists and reads files. Names are filesystem paths as given on the
+// command line, either absolute or relative to the current working directory.
+// Implementations must be safe for concurrent use.
+type Source interface {
+	// Open opens the named file for reading.
+	Open(name string) (io.ReadCloser, error)
+	// Stat returns information about the named file or directory.
+	Stat(name string) (fs.FileInfo, error)
+	// ReadDir returns the entries of the named directory, sorted by name.
+	ReadDir(name string) ([]fs.DirEntry, error)
+}
+
+// LinkResolver is implemented by sources whose symbolic links to
+// directories are followed when walking them. Links in other sources,
+// such as the tree of a git revision, are not followed.
+type LinkResolver interface {
+	// RealPath returns the named path with all symbolic links resolved.
+	RealPath(name string) (string, error)
+}
+
+// ReadFile reads the whole named file from src.
+func ReadFile(src Source, name string) ([]byte, error) {
+	f, err := src.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// OS is a Source reading from the local filesystem.
+type OS struct{}
+
+// NewOS creates a Source reading from the local filesystem.
+func NewOS() *OS {
+	return &OS{}
+}
+
+func (*OS) Open(name string) (io.ReadCloser, error)    { return os.Open(name) }
+func (*OS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (*OS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (*OS) RealPath(name string) (string, error)       { return filepath.EvalSymlinks(name) }
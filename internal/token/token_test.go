@@ -0,0 +1,50 @@
+package token
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimator_Count(t *testing.T) {
+	testCases := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"single short word", "hello", 1},
+		{"words absorb a leading space", "go test run", 3},
+		{"long identifier", "configuration", 3},
+		{"spaces merge into punctuation", "a := b", 3},
+		{"line break after punctuation", "{\n}\n", 2},
+		{"digits are grouped by three", "1234567", 3},
+		{"punctuation run", "!=", 1},
+		{"newline and indentation are one token", "\n\t\treturn", 2},
+		{"cjk characters are counted individually", "你好", 2},
+		{"invalid utf-8", "\xff\xfe", 2},
+	}
+
+	e := NewEstimator()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := e.Count([]byte(tc.text)); got != tc.want {
+				t.Errorf("Count(%q) = %d, want %d", tc.text, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEstimator_CountIsProportional(t *testing.T) {
+	e := NewEstimator()
+	line := "func (p *Packer) Plan(targets []string) ([]PlannedFile, error) {\n"
+
+	one := e.Count([]byte(line))
+	hundred := e.Count([]byte(strings.Repeat(line, 100)))
+	if hundred != 100*one {
+		t.Errorf("Count of 100 repeated lines = %d, want %d", hundred, 100*one)
+	}
+	// cl100k_base encodes this line in about 20 tokens; the estimate should be close.
+	if one < 15 || one > 25 {
+		t.Errorf("Count(%q) = %d, want a value near 20", line, one)
+	}
+}
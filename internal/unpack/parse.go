@@ -0,0 +1,209 @@
+// Package unpack reconstructs files from a document packed by syntex in the
+// Markdown or Org format.
+package unpack
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// File is a file reconstructed from a pack. Path is slash-separated, as
+// written in the pack.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// partialName matches the names of blocks that do not hold a whole file:
+// line ranges and symbols, such as "main.go#run:40-120", and diffs.
+var partialName = regexp.MustCompile(`:\d+-\d+$| \(diff\)$`)
+
+// DetectFormat guesses the format of a pack from the name of its file and
+// its content, returning "org" or "markdown".
+func DetectFormat(name string, content []byte) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".org":
+		return "org"
+	case ".md", ".markdown":
+		return "markdown"
+	}
+
+	// The first block following a file item decides.
+	lines := strings.Split(string(content), "\n")
+	for i := 1; i < len(lines); i++ {
+		if _, ok := itemName(lines, i); !ok {
+			continue
+		}
+		if _, ok := orgBlockLanguage(lines[i]); ok {
+			return "org"
+		}
+		if _, ok := openingFence(lines[i]); ok {
+			return "markdown"
+		}
+	}
+	return "markdown"
+}
+
+// Parse returns the files of a pack in the given format, "markdown", "md"
+// or "org", in the order they appear. A file is a code block directly
+// preceded by a "- path" list item, as written by the packer's formatters;
+// other text is ignored. Blocks holding part of a file or a diff cannot be
+// unpacked and their names are returned as skipped.
+//
+// Packing always ends a block with a newline, so a file without a final
+// newline gets one.
+func Parse(content []byte, format string) (files []File, skipped []string, err error) {
+	lines := strings.Split(string(content), "\n")
+
+	var blocks []File
+	switch format {
+	case "markdown", "md":
+		blocks, err = parseMarkdown(lines)
+	case "org":
+		blocks, err = parseOrg(lines)
+	default:
+		return nil, nil, fmt.Errorf("unsupported pack format %q (supported: markdown, org)", format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, block := range blocks {
+		if partialName.MatchString(block.Path) {
+			skipped = append(skipped, block.Path)
+			continue
+		}
+		files = append(files, block)
+	}
+	return files, skipped, nil
+}
+
+// parseMarkdown returns the named code blocks of a Markdown pack. A block
+// is closed by the first line repeating its opening fence, which the packer
+// makes longer than any run of backticks in the content.
+func parseMarkdown(lines []string) ([]File, error) {
+	var blocks []File
+	for i := 0; i < len(lines); i++ {
+		fence, ok := openingFence(lines[i])
+		if !ok {
+			continue
+		}
+
+		end := i + 1
+		for end < len(lines) && trimLine(lines[end]) != fence {
+			end++
+		}
+		if end == len(lines) {
+			return nil, fmt.Errorf("line %d: code block is not closed", i+1)
+		}
+
+		if name, ok := itemName(lines, i); ok {
+			blocks = append(blocks, File{Path: name, Content: markdownContent(lines[i+1 : end])})
+		}
+		i = end
+	}
+	return blocks, nil
+}
+
+// openingFence returns the backtick fence opening a code block on line.
+func openingFence(line string) (string, bool) {
+	line = trimLine(line)
+	n := len(line) - len(strings.TrimLeft(line, "`"))
+	if n < 3 || strings.Contains(line[n:], "`") {
+		return "", false
+	}
+	return line[:n], true
+}
+
+// parseOrg returns the named source blocks of an Org pack, removing the
+// comma the packer adds to escape lines of Org files.
+func parseOrg(lines []string) ([]File, error) {
+	var blocks []File
+	for i := 0; i < len(lines); i++ {
+		language, ok := orgBlockLanguage(lines[i])
+		if !ok {
+			continue
+		}
+
+		end := i + 1
+		for end < len(lines) && !strings.EqualFold(trimLine(lines[end]), "#+END_SRC") {
+			end++
+		}
+		if end == len(lines) {
+			return nil, fmt.Errorf("line %d: source block is not closed", i+1)
+		}
+
+		if name, ok := itemName(lines, i); ok {
+			body := lines[i+1 : end]
+			if language == "org" {
+				body = unescapeOrgLines(body)
+			}
+			blocks = append(blocks, File{Path: name, Content: orgContent(body)})
+		}
+		i = end
+	}
+	return blocks, nil
+}
+
+// orgBlockLanguage returns the language of the source block opened on line.
+func orgBlockLanguage(line string) (string, bool) {
+	line = trimLine(line)
+	const begin = "#+BEGIN_SRC"
+	if len(line) < len(begin) || !strings.EqualFold(line[:len(begin)], begin) {
+		return "", false
+	}
+	rest := line[len(begin):]
+	if rest != "" && rest[0] != ' ' {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// unescapeOrgLines reverses the packer's escaping of Org content, which
+// prepends a comma to every line starting with "*", "#+" or a comma.
+func unescapeOrgLines(lines []string) []string {
+	unescaped := make([]string, len(lines))
+	for i, line := range lines {
+		unescaped[i] = strings.TrimPrefix(line, ",")
+	}
+	return unescaped
+}
+
+// itemName returns the path of the "- path" list item on the line before
+// the block opening at lines[i].
+func itemName(lines []string, i int) (string, bool) {
+	if i == 0 {
+		return "", false
+	}
+	name, ok := strings.CutPrefix(trimLine(lines[i-1]), "- ")
+	name = strings.TrimSpace(name)
+	return name, ok && name != ""
+}
+
+// markdownContent returns the content of a Markdown code block from its
+// lines. The packer writes a newline after the content before the closing
+// fence, so the lines joined are the content, to which only a missing
+// final newline is added.
+func markdownContent(lines []string) []byte {
+	content := strings.Join(lines, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return []byte(content)
+}
+
+// orgContent returns the content of an Org source block from its lines,
+// each ending with a newline.
+func orgContent(lines []string) []byte {
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// trimLine removes trailing spaces and carriage returns from a marker line.
+func trimLine(line string) string {
+	return strings.TrimRight(line, " \t\r")
+}
@@ -0,0 +1,224 @@
+package unpack
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jbwfu/syntex/internal/packer"
+)
+
+func TestParse_RoundTrip(t *testing.T) {
+	files := []File{
+		{Path: "main.go", Content: []byte("package main\n\nfunc main() {}\n")},
+		{Path: "docs/README.md", Content: []byte("# Title\n\n```sh\nmake build\n```\n\n- item\n")},
+		{Path: "notes.org", Content: []byte("* Heading\n#+BEGIN_SRC go\n,comma\n#+END_SRC\ntext\n")},
+		{Path: "empty.txt", Content: nil},
+		{Path: "blank.txt", Content: []byte("\n\n")},
+	}
+	languages := map[string]string{
+		"main.go":        "go",
+		"docs/README.md": "markdown",
+		"notes.org":      "org",
+		"empty.txt":      "text",
+		"blank.txt":      "text",
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	var plan []packer.PlannedFile
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file.Path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", file.Path, err)
+		}
+		if err := os.WriteFile(file.Path, file.Content, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", file.Path, err)
+		}
+		plan = append(plan, packer.PlannedFile{Path: file.Path, Language: languages[file.Path]})
+	}
+
+	formatters := map[string]packer.Formatter{
+		"markdown": packer.NewMarkdownFormatter(),
+		"org":      packer.NewOrgFormatter(),
+	}
+	for format, formatter := range formatters {
+		t.Run(format, func(t *testing.T) {
+			var out bytes.Buffer
+			header := &packer.Header{Title: "demo", GeneratedAt: time.Now()}
+			p := packer.NewPacker(formatter, &out, nil, nil, packer.Options{Header: header, Tree: true})
+			if err := p.Execute(plan); err != nil {
+				t.Fatalf("Execute() returned an unexpected error: %v", err)
+			}
+
+			if got := DetectFormat("", out.Bytes()); got != format {
+				t.Errorf("DetectFormat() = %q, want %q", got, format)
+			}
+			got, skipped, err := Parse(out.Bytes(), format)
+			if err != nil {
+				t.Fatalf("Parse() returned an unexpected error: %v", err)
+			}
+			if len(skipped) > 0 {
+				t.Errorf("Parse() skipped %v, want none", skipped)
+			}
+			if len(got) != len(files) {
+				t.Fatalf("Parse() returned %d files, want %d:\n%s", len(got), len(files), out.String())
+			}
+			for i, file := range files {
+				if got[i].Path != file.Path || string(got[i].Content) != string(file.Content) {
+					t.Errorf("file %d = %s %q, want %s %q", i, got[i].Path, got[i].Content, file.Path, file.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name        string
+		format      string
+		pack        string
+		wantFiles   []File
+		wantSkipped []string
+		wantErr     bool
+	}{
+		{
+			name:      "missing final newline is added",
+			format:    "markdown",
+			pack:      "- a.go\n```go\npackage a\n```\n",
+			wantFiles: []File{{Path: "a.go", Content: []byte("package a\n")}},
+		},
+		{
+			name:        "partial and diff blocks are skipped",
+			format:      "markdown",
+			pack:        "- a.go:1-2\n```go\nx\n\n```\n\n- a.go (diff)\n```diff\n+x\n\n```\n",
+			wantSkipped: []string{"a.go:1-2", "a.go (diff)"},
+		},
+		{
+			name:      "code blocks without a file item are ignored",
+			format:    "md",
+			pack:      "Some text\n```text\n- fake\n```\n- b.txt\n```text\nb\n\n```\n",
+			wantFiles: []File{{Path: "b.txt", Content: []byte("b\n")}},
+		},
+		{
+			name:    "unclosed markdown block",
+			format:  "markdown",
+			pack:    "- a.go\n````go\n```\n",
+			wantErr: true,
+		},
+		{
+			name:      "org escaping is only removed from org files",
+			format:    "org",
+			pack:      "- a.org\n#+BEGIN_SRC org\n,* h\n,,x\n#+END_SRC\n\n- b.csv\n#+begin_src csv\n,x\n#+end_src\n",
+			wantFiles: []File{{Path: "a.org", Content: []byte("* h\n,x\n")}, {Path: "b.csv", Content: []byte(",x\n")}},
+		},
+		{
+			name:    "unclosed org block",
+			format:  "org",
+			pack:    "- a.go\n#+BEGIN_SRC go\nx\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown format",
+			format:  "xml",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			files, skipped, err := Parse([]byte(tc.pack), tc.format)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Parse() should have failed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() returned an unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(files, tc.wantFiles) {
+				t.Errorf("Parse() files = %q, want %q", files, tc.wantFiles)
+			}
+			if !reflect.DeepEqual(skipped, tc.wantSkipped) {
+				t.Errorf("Parse() skipped = %v, want %v", skipped, tc.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	t.Run("unsafe paths", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, path := range []string{"../escape.txt", "a/../../escape.txt", "/etc/escape.txt", ""} {
+			_, err := Write(dir, []File{{Path: path}}, Options{})
+			if !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("Write(%q) error = %v, want ErrUnsafePath", path, err)
+			}
+		}
+	})
+
+	t.Run("symbolic links cannot lead outside", func(t *testing.T) {
+		dir, outside := t.TempDir(), t.TempDir()
+		if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+		if _, err := Write(dir, []File{{Path: "link/x.txt", Content: []byte("x")}}, Options{}); err == nil {
+			t.Error("Write() through a symlink leaving the directory should fail")
+		}
+		if _, err := os.Stat(filepath.Join(outside, "x.txt")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("file was written outside the directory: %v", err)
+		}
+	})
+
+	t.Run("dry run writes nothing", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "out")
+		results, err := Write(dir, []File{{Path: "a/b.txt", Content: []byte("b")}}, Options{DryRun: true})
+		if err != nil {
+			t.Fatalf("Write() returned an unexpected error: %v", err)
+		}
+		if want := []Result{{Path: filepath.Join("a", "b.txt")}}; !reflect.DeepEqual(results, want) {
+			t.Errorf("Write() = %v, want %v", results, want)
+		}
+		if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("dry run created the directory: %v", err)
+		}
+	})
+
+	t.Run("existing files", func(t *testing.T) {
+		dir := t.TempDir()
+		existing := filepath.Join(dir, "a", "old.txt")
+		os.MkdirAll(filepath.Dir(existing), 0755)
+		os.WriteFile(existing, []byte("old"), 0644)
+		files := []File{{Path: "new.txt", Content: []byte("new")}, {Path: "a/old.txt", Content: []byte("replaced")}}
+
+		if _, err := Write(dir, files, Options{}); !errors.Is(err, ErrExists) {
+			t.Fatalf("Write() error = %v, want ErrExists", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "new.txt")); !errors.Is(err, os.ErrNotExist) {
+			t.Error("Write() wrote files before failing on an existing file")
+		}
+
+		results, err := Write(dir, files, Options{Overwrite: true})
+		if err != nil {
+			t.Fatalf("Write() with Overwrite returned an unexpected error: %v", err)
+		}
+		want := []Result{{Path: "new.txt"}, {Path: filepath.Join("a", "old.txt"), Exists: true}}
+		if !reflect.DeepEqual(results, want) {
+			t.Errorf("Write() = %v, want %v", results, want)
+		}
+		if content, _ := os.ReadFile(existing); string(content) != "replaced" {
+			t.Errorf("existing file content = %q, want %q", content, "replaced")
+		}
+	})
+
+	t.Run("duplicate paths", func(t *testing.T) {
+		files := []File{{Path: "a.txt"}, {Path: "./a.txt"}}
+		if _, err := Write(t.TempDir(), files, Options{}); err == nil {
+			t.Error("Write() with a duplicate path should fail")
+		}
+	})
+}
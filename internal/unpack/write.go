@@ -0,0 +1,143 @@
+package unpack
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrUnsafePath is returned for a file whose path is absolute or leaves
+	// the target directory.
+	ErrUnsafePath = errors.New("unsafe path")
+	// ErrExists is returned for files that already exist when overwriting
+	// is not allowed.
+	ErrExists = errors.New("file already exists")
+)
+
+// Options controls how files are written.
+type Options struct {
+	// DryRun reports the files that would be written without writing them.
+	DryRun bool
+	// Overwrite allows replacing existing files.
+	Overwrite bool
+}
+
+// Result describes a file written, or that would be written in a dry run.
+type Result struct {
+	// Path is the file's path in the target directory.
+	Path string
+	// Exists is true if the file replaced, or would replace, an existing file.
+	Exists bool
+}
+
+// Write writes files below dir, creating it and any missing parent
+// directories. All paths are checked before anything is written: a path
+// that is absolute or leaves dir fails with ErrUnsafePath, and unless
+// opts.Overwrite is set, existing files fail with ErrExists. Files are
+// written through an os.Root, so symbolic links cannot lead outside dir.
+func Write(dir string, files []File, opts Options) ([]Result, error) {
+	results := make([]Result, 0, len(files))
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		path := filepath.FromSlash(file.Path)
+		if !filepath.IsLocal(path) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsafePath, file.Path)
+		}
+		path = filepath.Clean(path)
+		if seen[path] {
+			return nil, fmt.Errorf("%s appears more than once in the pack", file.Path)
+		}
+		seen[path] = true
+		results = append(results, Result{Path: path})
+	}
+
+	root, err := openRoot(dir, opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	if root != nil {
+		defer root.Close()
+	}
+
+	existing, err := markExisting(root, results)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 && !opts.Overwrite {
+		return nil, fmt.Errorf("%w: %s", ErrExists, strings.Join(existing, ", "))
+	}
+	if opts.DryRun {
+		return results, nil
+	}
+
+	for i, file := range files {
+		if err := writeFile(root, results[i].Path, file.Content); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// openRoot opens dir as an os.Root, creating it unless in a dry run. In a
+// dry run, a missing dir yields a nil Root, as no file exists yet.
+func openRoot(dir string, dryRun bool) (*os.Root, error) {
+	if dryRun {
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenRoot(dir)
+}
+
+// markExisting sets Exists on the results naming existing files in root,
+// which may be nil, and returns their paths.
+func markExisting(root *os.Root, results []Result) ([]string, error) {
+	if root == nil {
+		return nil, nil
+	}
+	var existing []string
+	for i, result := range results {
+		info, err := root.Lstat(result.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("%s is a directory", result.Path)
+		}
+		results[i].Exists = true
+		existing = append(existing, result.Path)
+	}
+	return existing, nil
+}
+
+// writeFile writes content to the file at path in root, creating its parent directories.
+func writeFile(root *os.Root, path string, content []byte) error {
+	if dir := filepath.Dir(path); dir != "." {
+		parts := strings.Split(dir, string(filepath.Separator))
+		for i := range parts {
+			err := root.Mkdir(filepath.Join(parts[:i+1]...), 0755)
+			if err != nil && !errors.Is(err, fs.ErrExist) {
+				return err
+			}
+		}
+	}
+
+	f, err := root.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}